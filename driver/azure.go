@@ -0,0 +1,218 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+)
+
+type AzureDriver struct {
+	InitialVersion semver.Version
+
+	Servicer      AzureServicer
+	ContainerName string
+	BlobName      string
+}
+
+func (d *AzureDriver) Bump(b version.Bump) (semver.Version, error) {
+	return retryOnConflict(b, d.readCurrentVersion, func(newVersion semver.Version, etag string) error {
+		return d.Servicer.PutBlobIfMatch(d.ContainerName, d.BlobName, []byte(newVersion.String()), etag)
+	}, isAzureConditionNotMet)
+}
+
+func (d *AzureDriver) Set(v semver.Version) error {
+	return d.Servicer.PutBlob(d.ContainerName, d.BlobName, []byte(v.String()))
+}
+
+func (d *AzureDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	v, _, err := d.readVersion()
+	if isAzureBlobNotFound(err) {
+		if cursor == nil {
+			return []semver.Version{d.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return []semver.Version{v}, nil
+}
+
+// readVersion returns the current version and the ETag of the blob it was
+// read from.
+func (d *AzureDriver) readVersion() (semver.Version, string, error) {
+	contents, etag, err := d.Servicer.GetBlob(d.ContainerName, d.BlobName)
+	if err != nil {
+		return semver.Version{}, "", err
+	}
+
+	v, err := semver.Parse(strings.TrimSpace(string(contents)))
+	if err != nil {
+		return semver.Version{}, "", fmt.Errorf("parsing number in blob: %s", err)
+	}
+
+	return v, etag, nil
+}
+
+// readCurrentVersion is readVersion falling back to the initial version, with
+// an empty ETag, when the blob does not exist.
+func (d *AzureDriver) readCurrentVersion() (semver.Version, string, error) {
+	v, etag, err := d.readVersion()
+	if isAzureBlobNotFound(err) {
+		return d.InitialVersion, "", nil
+	}
+
+	return v, etag, err
+}
+
+func isAzureBlobNotFound(err error) bool {
+	return bloberror.HasCode(err, bloberror.BlobNotFound)
+}
+
+// isAzureConditionNotMet reports whether a conditional write lost a race: the
+// ETag no longer matched, or the blob was created by someone else first.
+func isAzureConditionNotMet(err error) bool {
+	return bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists)
+}
+
+type AzureServicer interface {
+	// GetBlob returns the contents of the blob along with its ETag.
+	GetBlob(containerName, blobName string) ([]byte, string, error)
+	PutBlob(containerName, blobName string, contents []byte) error
+	// PutBlobIfMatch only writes the blob if its ETag still matches; an empty
+	// ETag requires that the blob does not exist.
+	PutBlobIfMatch(containerName, blobName string, contents []byte, etag string) error
+}
+
+type AzureBlobServicer struct {
+	Client *azblob.Client
+}
+
+// NewAzureBlobServicer authenticates with whichever of the storage account
+// key, SAS token or service principal the source configures.
+func NewAzureBlobServicer(source models.Source) (*AzureBlobServicer, error) {
+	serviceURL := source.Endpoint
+	if serviceURL == "" {
+		if source.StorageAccount == "" {
+			return nil, fmt.Errorf("must specify storage_account or endpoint for the azure driver")
+		}
+
+		serviceURL = fmt.Sprintf("https://%s.blob.core.windows.net/", source.StorageAccount)
+	}
+
+	var methods int
+	for _, configured := range []bool{
+		source.StorageAccountKey != "",
+		source.SASToken != "",
+		source.ClientID != "" || source.ClientSecret != "",
+	} {
+		if configured {
+			methods++
+		}
+	}
+
+	if methods != 1 {
+		return nil, fmt.Errorf("must specify exactly one of storage_account_key, sas_token or client_id/client_secret for the azure driver")
+	}
+
+	var client *azblob.Client
+	var err error
+
+	switch {
+	case source.StorageAccountKey != "":
+		if source.StorageAccount == "" {
+			return nil, fmt.Errorf("must specify storage_account with storage_account_key for the azure driver")
+		}
+
+		var cred *azblob.SharedKeyCredential
+		cred, err = azblob.NewSharedKeyCredential(source.StorageAccount, source.StorageAccountKey)
+		if err != nil {
+			return nil, err
+		}
+
+		client, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+
+	case source.SASToken != "":
+		client, err = azblob.NewClientWithNoCredential(serviceURL+"?"+strings.TrimPrefix(source.SASToken, "?"), nil)
+
+	default:
+		var cred azcore.TokenCredential
+		cred, err = azidentity.NewClientSecretCredential(source.TenantID, source.ClientID, source.ClientSecret, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		client, err = azblob.NewClient(serviceURL, cred, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &AzureBlobServicer{Client: client}, nil
+}
+
+func (s *AzureBlobServicer) blockBlob(containerName, blobName string) *blockblob.Client {
+	return s.Client.ServiceClient().NewContainerClient(containerName).NewBlockBlobClient(blobName)
+}
+
+func (s *AzureBlobServicer) GetBlob(containerName, blobName string) ([]byte, string, error) {
+	resp, err := s.Client.DownloadStream(context.Background(), containerName, blobName, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	contents, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if resp.ETag == nil {
+		return nil, "", errors.New("blob has no ETag")
+	}
+
+	return contents, string(*resp.ETag), nil
+}
+
+func (s *AzureBlobServicer) PutBlob(containerName, blobName string, contents []byte) error {
+	return s.upload(containerName, blobName, contents, nil)
+}
+
+func (s *AzureBlobServicer) PutBlobIfMatch(containerName, blobName string, contents []byte, etag string) error {
+	conditions := &blob.ModifiedAccessConditions{}
+	if etag == "" {
+		conditions.IfNoneMatch = to.Ptr(azcore.ETagAny)
+	} else {
+		conditions.IfMatch = to.Ptr(azcore.ETag(etag))
+	}
+
+	return s.upload(containerName, blobName, contents, conditions)
+}
+
+func (s *AzureBlobServicer) upload(containerName, blobName string, contents []byte, conditions *blob.ModifiedAccessConditions) error {
+	_, err := s.blockBlob(containerName, blobName).Upload(
+		context.Background(),
+		streaming.NopCloser(bytes.NewReader(contents)),
+		&blockblob.UploadOptions{
+			HTTPHeaders:      &blob.HTTPHeaders{BlobCacheControl: to.Ptr("private")},
+			AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: conditions},
+		},
+	)
+	return err
+}
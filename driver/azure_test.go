@@ -0,0 +1,207 @@
+package driver_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Azure Driver", func() {
+	var (
+		store *fakeBlobStore
+		d     *driver.AzureDriver
+	)
+
+	BeforeEach(func() {
+		d, store = newFakeAzureDriver()
+	})
+
+	Describe("Check", func() {
+		It("returns the initial version when the blob does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+			Expect(store.paths).To(ConsistOf("/versions/some-version"))
+		})
+
+		It("returns the version in the blob", func() {
+			store.put("2.3.4")
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("only creates the blob if it still does not exist", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(store.body).To(Equal("1.1.0"))
+			Expect(store.conditions).To(Equal([]string{"If-None-Match: *"}))
+		})
+
+		It("writes conditionally on the ETag it read", func() {
+			store.put("1.2.3")
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(store.body).To(Equal("1.2.4"))
+			Expect(store.conditions).To(Equal([]string{`If-Match: "1"`}))
+		})
+	})
+
+	Describe("Set", func() {
+		It("writes the blob unconditionally", func() {
+			store.put("1.2.3")
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.body).To(Equal("5.0.0"))
+			Expect(store.conditions).To(Equal([]string{""}))
+		})
+	})
+
+	Describe("NewAzureBlobServicer", func() {
+		It("requires exactly one way to authenticate", func() {
+			_, err := driver.NewAzureBlobServicer(models.Source{StorageAccount: "account"})
+			Expect(err).To(MatchError(ContainSubstring("exactly one of")))
+
+			_, err = driver.NewAzureBlobServicer(models.Source{
+				StorageAccount:    "account",
+				StorageAccountKey: "a2V5",
+				SASToken:          "sv=2020-10-02",
+			})
+			Expect(err).To(MatchError(ContainSubstring("exactly one of")))
+		})
+
+		It("requires the storage account without an endpoint", func() {
+			_, err := driver.NewAzureBlobServicer(models.Source{SASToken: "sv=2020-10-02"})
+			Expect(err).To(MatchError(ContainSubstring("storage_account")))
+		})
+	})
+})
+
+// fakeBlobStore serves a single block blob, honouring the conditional headers
+// and error codes of the Blob service.
+type fakeBlobStore struct {
+	body   string
+	exists bool
+	etag   int
+
+	concurrentWrites []string
+	conditions       []string
+	paths            []string
+}
+
+func (s *fakeBlobStore) put(body string) {
+	s.body = body
+	s.exists = true
+	s.etag++
+}
+
+func (s *fakeBlobStore) currentETag() string {
+	return fmt.Sprintf(`"%d"`, s.etag)
+}
+
+func (s *fakeBlobStore) fail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+}
+
+func (s *fakeBlobStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.paths = append(s.paths, r.URL.Path)
+
+	switch r.Method {
+	case http.MethodGet:
+		if !s.exists {
+			s.fail(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+
+		w.Header().Set("ETag", s.currentETag())
+		io.WriteString(w, s.body)
+
+	case http.MethodPut:
+		ifMatch := r.Header.Get("If-Match")
+		ifNoneMatch := r.Header.Get("If-None-Match")
+
+		switch {
+		case ifMatch != "":
+			s.conditions = append(s.conditions, "If-Match: "+ifMatch)
+		case ifNoneMatch != "":
+			s.conditions = append(s.conditions, "If-None-Match: "+ifNoneMatch)
+		default:
+			s.conditions = append(s.conditions, "")
+		}
+
+		if len(s.concurrentWrites) > 0 {
+			s.put(s.concurrentWrites[0])
+			s.concurrentWrites = s.concurrentWrites[1:]
+		}
+
+		if ifNoneMatch == "*" && s.exists {
+			s.fail(w, http.StatusConflict, "BlobAlreadyExists")
+			return
+		}
+
+		if ifMatch != "" && (!s.exists || ifMatch != s.currentETag()) {
+			s.fail(w, http.StatusPreconditionFailed, "ConditionNotMet")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.put(string(body))
+		w.Header().Set("ETag", s.currentETag())
+		w.WriteHeader(http.StatusCreated)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeAzureDriver() (*driver.AzureDriver, *fakeBlobStore) {
+	store := &fakeBlobStore{}
+	server := httptest.NewServer(store)
+	DeferCleanup(server.Close)
+
+	servicer, err := driver.NewAzureBlobServicer(models.Source{
+		Endpoint: server.URL + "/",
+		SASToken: "?sv=2020-10-02&sig=secret",
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	return &driver.AzureDriver{
+		InitialVersion: semver.Version{Major: 1},
+		Servicer:       servicer,
+		ContainerName:  "versions",
+		BlobName:       "some-version",
+	}, store
+}
+
+func azureCASSubject() casSubject {
+	d, store := newFakeAzureDriver()
+
+	return casSubject{
+		driver:  d,
+		store:   store.put,
+		current: func() string { return store.body },
+		race:    func(versions ...string) { store.concurrentWrites = versions },
+	}
+}
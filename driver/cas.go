@@ -0,0 +1,58 @@
+package driver
+
+import (
+	"errors"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/version"
+)
+
+// errModifiedConcurrently is wrapped by the errors of writes that lost a race
+// with another writer on stores that report it without an error of their own.
+var errModifiedConcurrently = errors.New("modified concurrently")
+
+// retryOnConflict bumps the version with compare-and-swap. read returns the
+// current version, or the initial version if there is none yet, along with
+// what write needs to only succeed if the version is still the one that was
+// read, e.g. an ETag. When the write loses a race with another writer, as
+// reported by isConflict, the version is read again and the bump re-applied on
+// top of it, up to RetriesOnErrorWriteVersion times.
+func retryOnConflict[T any](
+	bump version.Bump,
+	read func() (semver.Version, T, error),
+	write func(semver.Version, T) error,
+	isConflict func(error) bool,
+) (semver.Version, error) {
+	var err error
+
+	for range RetriesOnErrorWriteVersion {
+		var currentVersion semver.Version
+		var token T
+		currentVersion, token, err = read()
+		if err != nil {
+			return semver.Version{}, err
+		}
+
+		var newVersion semver.Version
+		newVersion, err = version.Apply(bump, currentVersion)
+		if err != nil {
+			return semver.Version{}, err
+		}
+
+		err = write(newVersion, token)
+		if err == nil {
+			return newVersion, nil
+		}
+
+		if !isConflict(err) {
+			return semver.Version{}, err
+		}
+	}
+
+	return semver.Version{}, err
+}
+
+func isModifiedConcurrently(err error) bool {
+	return errors.Is(err, errModifiedConcurrently)
+}
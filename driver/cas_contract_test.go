@@ -0,0 +1,105 @@
+package driver_test
+
+import (
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// casSubject is a driver bumping with compare-and-swap against a fake store,
+// starting from the initial version 1.0.0.
+type casSubject struct {
+	driver driver.Driver
+
+	// store writes a version as another writer would.
+	store func(string)
+	// current returns the stored version.
+	current func() string
+	// race makes another writer store each of the versions right before the
+	// next conditional writes of the driver.
+	race func(...string)
+}
+
+var _ = Describe("Compare-and-swap drivers", func() {
+	for _, entry := range []struct {
+		name    string
+		subject func() casSubject
+	}{
+		{"Azure", azureCASSubject},
+		{"Consul", consulCASSubject},
+		{"DynamoDB", dynamoDBCASSubject},
+		{"GCS", gcsCASSubject},
+		{"HTTP", httpCASSubject},
+		{"Kubernetes", kubernetesCASSubject},
+		{"Redis", redisCASSubject},
+		{"S3", s3CASSubject},
+		{"Vault", vaultCASSubject},
+	} {
+		Describe(entry.name, func() {
+			var subject casSubject
+
+			BeforeEach(func() {
+				subject = entry.subject()
+			})
+
+			It("bumps the initial version when nothing is stored", func() {
+				newVersion, err := subject.driver.Bump(version.MinorBump{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(newVersion.String()).To(Equal("1.1.0"))
+				Expect(subject.current()).To(Equal("1.1.0"))
+			})
+
+			It("re-reads and re-applies the bump after losing a race", func() {
+				subject.store("1.2.3")
+				subject.race("1.5.0")
+
+				newVersion, err := subject.driver.Bump(version.PatchBump{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(newVersion.String()).To(Equal("1.5.1"))
+				Expect(subject.current()).To(Equal("1.5.1"))
+			})
+
+			It("re-reads and re-applies the bump when another writer stored the first version", func() {
+				subject.race("1.5.0")
+
+				newVersion, err := subject.driver.Bump(version.PatchBump{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(newVersion.String()).To(Equal("1.5.1"))
+				Expect(subject.current()).To(Equal("1.5.1"))
+			})
+
+			It("refuses a checked bump without writing", func() {
+				subject.store("1.2.3")
+
+				_, err := subject.driver.Bump(version.PromoteBump{Channels: version.PreReleaseChannels{"rc"}})
+				Expect(err).To(MatchError(ContainSubstring("already a final version")))
+				Expect(subject.current()).To(Equal("1.2.3"))
+			})
+
+			It("checks the bump against the version it is re-applied to after losing a race", func() {
+				subject.store("1.0.0-rc.1")
+				subject.race("1.0.0")
+
+				channels := version.PreReleaseChannels{"rc"}
+				_, err := subject.driver.Bump(version.GuardBump{
+					Bump:  version.SetBump{Version: semver.MustParse("1.0.0-rc.2")},
+					Guard: channels.CheckOrder,
+				})
+				Expect(err).To(MatchError(ContainSubstring("refusing to move 1.0.0 back to 1.0.0-rc.2")))
+				Expect(subject.current()).To(Equal("1.0.0"))
+			})
+
+			It("gives up after repeatedly losing races", func() {
+				subject.store("1.2.3")
+				subject.race("1.3.0", "1.4.0", "1.5.0")
+
+				_, err := subject.driver.Bump(version.PatchBump{})
+				Expect(err).To(HaveOccurred())
+				Expect(subject.current()).To(Equal("1.5.0"))
+			})
+		})
+	}
+})
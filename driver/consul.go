@@ -0,0 +1,103 @@
+package driver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/hashicorp/consul/api"
+
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+)
+
+type ConsulDriver struct {
+	InitialVersion semver.Version
+
+	Client *api.Client
+	Key    string
+}
+
+func NewConsulClient(source models.Source) (*api.Client, error) {
+	config := api.DefaultNonPooledConfig()
+	config.Address = source.ConsulAddress
+	config.Token = source.ConsulToken
+	config.Datacenter = source.ConsulDatacenter
+	config.TLSConfig.InsecureSkipVerify = source.SkipSSLVerification
+
+	return api.NewClient(config)
+}
+
+func (d *ConsulDriver) Bump(b version.Bump) (semver.Version, error) {
+	read := func() (semver.Version, uint64, error) {
+		v, modifyIndex, exists, err := d.readVersion()
+		if err == nil && !exists {
+			v = d.InitialVersion
+		}
+
+		return v, modifyIndex, err
+	}
+
+	return retryOnConflict(b, read, func(newVersion semver.Version, modifyIndex uint64) error {
+		// a ModifyIndex of 0 only writes the key if it does not exist yet
+		written, _, err := d.Client.KV().CAS(&api.KVPair{
+			Key:         d.Key,
+			Value:       []byte(newVersion.String()),
+			ModifyIndex: modifyIndex,
+		}, nil)
+		if err != nil {
+			return err
+		}
+
+		if !written {
+			return fmt.Errorf("key %s was %w", d.Key, errModifiedConcurrently)
+		}
+
+		return nil
+	}, isModifiedConcurrently)
+}
+
+func (d *ConsulDriver) Set(v semver.Version) error {
+	_, err := d.Client.KV().Put(&api.KVPair{
+		Key:   d.Key,
+		Value: []byte(v.String()),
+	}, nil)
+	return err
+}
+
+func (d *ConsulDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	v, _, exists, err := d.readVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		if cursor == nil {
+			return []semver.Version{d.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	}
+
+	// the key keeps no history, so only the current version can be reported
+	return []semver.Version{v}, nil
+}
+
+// readVersion returns the current version and the ModifyIndex of the key it
+// was read from.
+func (d *ConsulDriver) readVersion() (semver.Version, uint64, bool, error) {
+	pair, _, err := d.Client.KV().Get(d.Key, nil)
+	if err != nil {
+		return semver.Version{}, 0, false, err
+	}
+
+	if pair == nil {
+		return semver.Version{}, 0, false, nil
+	}
+
+	v, err := semver.Parse(strings.TrimSpace(string(pair.Value)))
+	if err != nil {
+		return semver.Version{}, 0, false, fmt.Errorf("parsing number in key %s: %s", d.Key, err)
+	}
+
+	return v, pair.ModifyIndex, true, nil
+}
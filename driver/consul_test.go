@@ -0,0 +1,178 @@
+package driver_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Consul Driver", func() {
+	var (
+		store *fakeConsulKV
+		d     *driver.ConsulDriver
+	)
+
+	BeforeEach(func() {
+		d, store = newFakeConsulDriver()
+	})
+
+	Describe("Check", func() {
+		It("returns the initial version when the key does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the version in the key", func() {
+			store.put("2.3.4")
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+			Expect(store.tokens).To(ConsistOf("some-token"))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("only creates the key if it still does not exist", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(store.value).To(Equal("1.1.0"))
+			Expect(store.cas).To(Equal([]string{"0"}))
+		})
+
+		It("writes with the ModifyIndex it read", func() {
+			store.put("1.2.3")
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(store.value).To(Equal("1.2.4"))
+			Expect(store.cas).To(Equal([]string{"1"}))
+		})
+	})
+
+	Describe("Set", func() {
+		It("writes the key unconditionally", func() {
+			store.put("1.2.3")
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.value).To(Equal("5.0.0"))
+			Expect(store.cas).To(Equal([]string{""}))
+		})
+	})
+})
+
+// fakeConsulKV serves a single key of the Consul KV store, honouring the cas
+// parameter on writes.
+type fakeConsulKV struct {
+	key string
+
+	value       string
+	exists      bool
+	modifyIndex int
+
+	concurrentWrites []string
+	cas              []string
+	tokens           []string
+}
+
+func (s *fakeConsulKV) put(value string) {
+	s.value = value
+	s.exists = true
+	s.modifyIndex++
+}
+
+func (s *fakeConsulKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/kv/"+s.key {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	s.tokens = append(s.tokens, r.Header.Get("X-Consul-Token"))
+	w.Header().Set("X-Consul-Index", strconv.Itoa(s.modifyIndex))
+
+	switch r.Method {
+	case http.MethodGet:
+		if !s.exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `[{"Key":%q,"Value":%q,"ModifyIndex":%d,"CreateIndex":1}]`,
+			s.key, base64.StdEncoding.EncodeToString([]byte(s.value)), s.modifyIndex)
+
+	case http.MethodPut:
+		cas, conditional := r.URL.Query()["cas"]
+		if conditional {
+			s.cas = append(s.cas, cas[0])
+		} else {
+			s.cas = append(s.cas, "")
+		}
+
+		if len(s.concurrentWrites) > 0 {
+			s.put(s.concurrentWrites[0])
+			s.concurrentWrites = s.concurrentWrites[1:]
+		}
+
+		if conditional && cas[0] != strconv.Itoa(s.modifyIndex) {
+			io.WriteString(w, "false")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.put(string(body))
+		io.WriteString(w, "true")
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeConsulDriver() (*driver.ConsulDriver, *fakeConsulKV) {
+	store := &fakeConsulKV{key: "releases/some-version"}
+	server := httptest.NewServer(store)
+	DeferCleanup(server.Close)
+
+	client, err := driver.NewConsulClient(models.Source{
+		ConsulAddress: server.URL,
+		ConsulToken:   "some-token",
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	return &driver.ConsulDriver{
+		InitialVersion: semver.Version{Major: 1},
+		Client:         client,
+		Key:            "releases/some-version",
+	}, store
+}
+
+func consulCASSubject() casSubject {
+	d, store := newFakeConsulDriver()
+
+	return casSubject{
+		driver:  d,
+		store:   store.put,
+		current: func() string { return store.value },
+		race:    func(versions ...string) { store.concurrentWrites = versions },
+	}
+}
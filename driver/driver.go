@@ -6,13 +6,16 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/blang/semver"
 	"github.com/concourse/semver-resource/models"
@@ -28,6 +31,19 @@ type Driver interface {
 const maxRetries = 12
 
 func FromSource(source models.Source) (Driver, error) {
+	driver, err := fromSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if source.EnforceIncreasing {
+		driver = &IncreasingDriver{Driver: driver}
+	}
+
+	return driver, nil
+}
+
+func fromSource(source models.Source) (Driver, error) {
 	var initialVersion semver.Version
 	if source.InitialVersion != "" {
 		version, err := semver.Parse(source.InitialVersion)
@@ -42,53 +58,9 @@ func FromSource(source models.Source) (Driver, error) {
 
 	switch source.Driver {
 	case models.DriverUnspecified, models.DriverS3:
-		var credsProvider aws.CredentialsProvider
-
-		if source.AccessKeyID != "" && source.SecretAccessKey != "" {
-			credsProvider = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(source.AccessKeyID, source.SecretAccessKey, source.SessionToken))
-			_, err := credsProvider.Retrieve(context.Background())
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		regionName := source.RegionName
-		if regionName == "" {
-			regionName = "us-east-1"
-		}
-
-		var httpClient *http.Client
-		if source.SkipSSLVerification {
-			httpClient = &http.Client{Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			}}
-		} else {
-			httpClient = http.DefaultClient
-		}
-
-		cfg, err := config.LoadDefaultConfig(context.TODO(),
-			config.WithRegion(regionName),
-			config.WithHTTPClient(httpClient),
-			config.WithRetryMaxAttempts(maxRetries),
-			config.WithCredentialsProvider(credsProvider),
-		)
+		cfg, err := newAWSConfig(source)
 		if err != nil {
-			return nil, fmt.Errorf("error loading default aws config: %w", err)
-		}
-
-		if source.AssumeRoleArn != "" {
-			stsClient := sts.NewFromConfig(cfg)
-			roleCreds := stscreds.NewAssumeRoleProvider(stsClient, source.AssumeRoleArn)
-			creds, err := roleCreds.Retrieve(context.TODO())
-			if err != nil {
-				return nil, fmt.Errorf("error assuming role: %w", err)
-			}
-
-			cfg.Credentials = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(
-				creds.AccessKeyID,
-				creds.SecretAccessKey,
-				creds.SessionToken,
-			))
+			return nil, err
 		}
 
 		s3Opts := []func(*s3.Options){
@@ -104,21 +76,12 @@ func FromSource(source models.Source) (Driver, error) {
 			})
 		}
 
-		if source.Endpoint != "" {
-			endpoint := source.Endpoint
-			u, err := url.Parse(source.Endpoint)
-			if err != nil {
-				return nil, fmt.Errorf("error parsing given endpoint: %w", err)
-			}
-			if u.Scheme == "" {
-				// source.Endpoint is a hostname
-				scheme := "https://"
-				if source.DisableSSL {
-					scheme = "http://"
-				}
-				endpoint = scheme + source.Endpoint
-			}
+		endpoint, err := awsEndpoint(source)
+		if err != nil {
+			return nil, err
+		}
 
+		if endpoint != "" {
 			s3Opts = append(s3Opts, func(o *s3.Options) {
 				o.BaseEndpoint = &endpoint
 			})
@@ -165,6 +128,21 @@ func FromSource(source models.Source) (Driver, error) {
 			SkipSSLVerification: source.SkipSSLVerification,
 		}, nil
 
+	case models.DriverGitTags:
+		return &GitTagsDriver{
+			InitialVersion: initialVersion,
+
+			URI:                 source.URI,
+			Branch:              source.Branch,
+			PrivateKey:          source.PrivateKey,
+			Username:            source.Username,
+			Password:            source.Password,
+			TagPrefix:           source.TagPrefix,
+			GitUser:             source.GitUser,
+			CommitMessage:       source.CommitMessage,
+			SkipSSLVerification: source.SkipSSLVerification,
+		}, nil
+
 	case models.DriverSwift:
 		return NewSwiftDriver(&source)
 
@@ -185,12 +163,360 @@ func FromSource(source models.Source) (Driver, error) {
 		return &GCSDriver{
 			InitialVersion: initialVersion,
 
-			Servicer:   servicer,
-			BucketName: source.Bucket,
-			Key:        source.Key,
+			Servicer:     servicer,
+			BucketName:   source.Bucket,
+			Key:          source.Key,
+			RetryBackoff: 100 * time.Millisecond,
+		}, nil
+
+	case models.DriverAzure:
+		servicer, err := NewAzureBlobServicer(source)
+		if err != nil {
+			return nil, err
+		}
+
+		return &AzureDriver{
+			InitialVersion: initialVersion,
+
+			Servicer:      servicer,
+			ContainerName: source.Container,
+			BlobName:      source.Key,
+		}, nil
+
+	case models.DriverVault:
+		if source.VaultPath == "" {
+			return nil, fmt.Errorf("must specify vault_path for the vault driver")
+		}
+
+		client, err := NewVaultClient(source)
+		if err != nil {
+			return nil, err
+		}
+
+		mount := source.VaultMount
+		if mount == "" {
+			mount = "secret"
+		}
+
+		field := source.VaultField
+		if field == "" {
+			field = "version"
+		}
+
+		return &VaultDriver{
+			InitialVersion: initialVersion,
+
+			Client: client,
+			Mount:  mount,
+			Path:   source.VaultPath,
+			Field:  field,
+		}, nil
+
+	case models.DriverConsul:
+		if source.Key == "" {
+			return nil, fmt.Errorf("must specify key for the consul driver")
+		}
+
+		client, err := NewConsulClient(source)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ConsulDriver{
+			InitialVersion: initialVersion,
+
+			Client: client,
+			Key:    source.Key,
+		}, nil
+
+	case models.DriverEtcd:
+		if len(source.EtcdEndpoints) == 0 {
+			return nil, fmt.Errorf("must specify etcd_endpoints for the etcd driver")
+		}
+
+		if source.Key == "" {
+			return nil, fmt.Errorf("must specify key for the etcd driver")
+		}
+
+		client, err := NewEtcdClient(source)
+		if err != nil {
+			return nil, err
+		}
+
+		return &EtcdDriver{
+			InitialVersion: initialVersion,
+
+			Client:         client,
+			Key:            source.Key,
+			RequestTimeout: 30 * time.Second,
+		}, nil
+
+	case models.DriverRedis:
+		if source.RedisAddress == "" {
+			return nil, fmt.Errorf("must specify redis_address for the redis driver")
+		}
+
+		if source.Key == "" {
+			return nil, fmt.Errorf("must specify key for the redis driver")
+		}
+
+		return &RedisDriver{
+			InitialVersion: initialVersion,
+
+			Client: NewRedisClient(source),
+			Key:    source.Key,
+		}, nil
+
+	case models.DriverSQL:
+		return NewSQLDriver(source, initialVersion)
+
+	case models.DriverKubernetes:
+		if source.Namespace == "" || source.ConfigMap == "" {
+			return nil, fmt.Errorf("must specify namespace and config_map for the kubernetes driver")
+		}
+
+		client, err := NewKubernetesClient(source)
+		if err != nil {
+			return nil, err
+		}
+
+		key := source.ConfigMapKey
+		if key == "" {
+			key = "version"
+		}
+
+		return &KubernetesDriver{
+			InitialVersion: initialVersion,
+
+			Client:        client,
+			Namespace:     source.Namespace,
+			ConfigMapName: source.ConfigMap,
+			Key:           key,
+		}, nil
+
+	case models.DriverSSM:
+		if source.Key == "" {
+			return nil, fmt.Errorf("must specify key for the ssm driver")
+		}
+
+		cfg, err := newAWSConfig(source)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint, err := awsEndpoint(source)
+		if err != nil {
+			return nil, err
+		}
+
+		ssmClient := ssm.NewFromConfig(cfg, func(o *ssm.Options) {
+			if endpoint != "" {
+				o.BaseEndpoint = &endpoint
+			}
+		})
+
+		return &SSMDriver{
+			InitialVersion: initialVersion,
+
+			Svc:           ssmClient,
+			ParameterName: source.Key,
+			LockTimeout:   time.Minute,
+			StaleLockAge:  10 * time.Minute,
+		}, nil
+
+	case models.DriverDynamoDB:
+		if source.DynamoDBTable == "" || source.Key == "" {
+			return nil, fmt.Errorf("must specify dynamodb_table and key for the dynamodb driver")
+		}
+
+		cfg, err := newAWSConfig(source)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint, err := awsEndpoint(source)
+		if err != nil {
+			return nil, err
+		}
+
+		dynamoDBClient := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+			if endpoint != "" {
+				o.BaseEndpoint = &endpoint
+			}
+		})
+
+		keyAttribute := source.DynamoDBKeyAttribute
+		if keyAttribute == "" {
+			keyAttribute = "id"
+		}
+
+		versionAttribute := source.DynamoDBVersionAttribute
+		if versionAttribute == "" {
+			versionAttribute = "version"
+		}
+
+		return &DynamoDBDriver{
+			InitialVersion: initialVersion,
+
+			Svc:              dynamoDBClient,
+			TableName:        source.DynamoDBTable,
+			KeyAttribute:     keyAttribute,
+			Key:              source.Key,
+			VersionAttribute: versionAttribute,
+		}, nil
+
+	case models.DriverHTTP:
+		if source.URI == "" {
+			return nil, fmt.Errorf("must specify uri for the http driver")
+		}
+
+		client, err := NewHTTPClient(source)
+		if err != nil {
+			return nil, err
+		}
+
+		return &HTTPDriver{
+			InitialVersion: initialVersion,
+
+			Client:      client,
+			URL:         source.URI,
+			Username:    source.Username,
+			Password:    source.Password,
+			BearerToken: source.BearerToken,
+			Headers:     source.Headers,
+		}, nil
+
+	case models.DriverSFTP:
+		if source.Host == "" || source.Path == "" {
+			return nil, fmt.Errorf("must specify host and path for the sftp driver")
+		}
+
+		clientConfig, err := NewSSHClientConfig(source)
+		if err != nil {
+			return nil, err
+		}
+
+		return &SFTPDriver{
+			InitialVersion: initialVersion,
+
+			Address:      source.Host,
+			ClientConfig: clientConfig,
+			Path:         source.Path,
+			LockTimeout:  time.Minute,
+			StaleLockAge: 10 * time.Minute,
+		}, nil
+
+	case models.DriverOCI:
+		if source.Repository == "" {
+			return nil, fmt.Errorf("must specify repository for the oci driver")
+		}
+
+		repository, options, err := NewOCIRepository(source)
+		if err != nil {
+			return nil, err
+		}
+
+		return &OCIDriver{
+			InitialVersion: initialVersion,
+
+			Repository: repository,
+			TagPrefix:  source.TagPrefix,
+			FromTag:    source.FromTag,
+			Options:    options,
+		}, nil
+
+	case models.DriverFile:
+		if source.Path == "" {
+			return nil, fmt.Errorf("must specify path for the file driver")
+		}
+
+		return &FileDriver{
+			InitialVersion: initialVersion,
+
+			Path: source.Path,
 		}, nil
 
 	default:
 		return nil, fmt.Errorf("unknown driver: %s", source.Driver)
 	}
 }
+
+// newAWSConfig builds the configuration shared by the drivers backed by AWS
+// services, from the credentials, region and role given in the source.
+func newAWSConfig(source models.Source) (aws.Config, error) {
+	var credsProvider aws.CredentialsProvider
+
+	if source.AccessKeyID != "" && source.SecretAccessKey != "" {
+		credsProvider = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(source.AccessKeyID, source.SecretAccessKey, source.SessionToken))
+		_, err := credsProvider.Retrieve(context.Background())
+		if err != nil {
+			return aws.Config{}, err
+		}
+	}
+
+	regionName := source.RegionName
+	if regionName == "" {
+		regionName = "us-east-1"
+	}
+
+	var httpClient *http.Client
+	if source.SkipSSLVerification {
+		httpClient = &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+	} else {
+		httpClient = http.DefaultClient
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(),
+		config.WithRegion(regionName),
+		config.WithHTTPClient(httpClient),
+		config.WithRetryMaxAttempts(maxRetries),
+		config.WithCredentialsProvider(credsProvider),
+	)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("error loading default aws config: %w", err)
+	}
+
+	if source.AssumeRoleArn != "" {
+		stsClient := sts.NewFromConfig(cfg)
+		roleCreds := stscreds.NewAssumeRoleProvider(stsClient, source.AssumeRoleArn)
+		creds, err := roleCreds.Retrieve(context.TODO())
+		if err != nil {
+			return aws.Config{}, fmt.Errorf("error assuming role: %w", err)
+		}
+
+		cfg.Credentials = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(
+			creds.AccessKeyID,
+			creds.SecretAccessKey,
+			creds.SessionToken,
+		))
+	}
+
+	return cfg, nil
+}
+
+// awsEndpoint returns the endpoint given in the source as a URL, or "" to use
+// the service's default endpoint.
+func awsEndpoint(source models.Source) (string, error) {
+	if source.Endpoint == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(source.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("error parsing given endpoint: %w", err)
+	}
+
+	if u.Scheme != "" {
+		return source.Endpoint, nil
+	}
+
+	// source.Endpoint is a hostname
+	scheme := "https://"
+	if source.DisableSSL {
+		scheme = "http://"
+	}
+
+	return scheme + source.Endpoint, nil
+}
@@ -2,8 +2,12 @@ package driver_test
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/concourse/semver-resource/driver"
 	"github.com/concourse/semver-resource/models"
 	. "github.com/onsi/ginkgo/v2"
@@ -46,9 +50,7 @@ var _ = Describe("Driver", func() {
 			Expect(transport.TLSClientConfig.InsecureSkipVerify).Should(BeTrue())
 		})
 	})
-})
 
-var _ = Describe("Driver", func() {
 	Context("Git", func() {
 		var src models.Source
 		BeforeEach(func() {
@@ -75,9 +77,335 @@ var _ = Describe("Driver", func() {
 			Expect(gitDriver.SkipSSLVerification).To(Not(BeNil()))
 		})
 	})
-})
 
-var _ = Describe("Driver", func() {
+	Context("enforce_increasing", func() {
+		It("wraps the driver to refuse versions that are not increasing", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:            models.DriverFile,
+				Path:              "/versions/app",
+				EnforceIncreasing: true,
+			})
+			Expect(err).To(BeNil())
+			increasingDriver, ok := aDriver.(*driver.IncreasingDriver)
+			Expect(ok).To(BeTrue())
+			Expect(increasingDriver.Driver).To(BeAssignableToTypeOf(&driver.FileDriver{}))
+		})
+		It("returns the driver as it is by default", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver: models.DriverFile,
+				Path:   "/versions/app",
+			})
+			Expect(err).To(BeNil())
+			Expect(aDriver).To(BeAssignableToTypeOf(&driver.FileDriver{}))
+		})
+	})
+
+	Context("OCI", func() {
+		It("returns an oci driver", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:     models.DriverOCI,
+				Repository: "registry.example.com/team/app",
+				TagPrefix:  "v",
+				FromTag:    "latest",
+			})
+			Expect(err).To(BeNil())
+			ociDriver, ok := aDriver.(*driver.OCIDriver)
+			Expect(ok).To(BeTrue())
+			Expect(ociDriver.Repository.String()).To(Equal("registry.example.com/team/app"))
+			Expect(ociDriver.TagPrefix).To(Equal("v"))
+			Expect(ociDriver.FromTag).To(Equal("latest"))
+		})
+		It("requires a repository", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverOCI})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("SFTP", func() {
+		It("returns an sftp driver", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:   models.DriverSFTP,
+				Host:     "files.example.com",
+				Path:     "/srv/versions/some-app",
+				Username: "semver",
+				Password: "secret",
+				HostKey:  "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl",
+			})
+			Expect(err).To(BeNil())
+			sftpDriver, ok := aDriver.(*driver.SFTPDriver)
+			Expect(ok).To(BeTrue())
+			Expect(sftpDriver.Address).To(Equal("files.example.com"))
+			Expect(sftpDriver.Path).To(Equal("/srv/versions/some-app"))
+			Expect(sftpDriver.ClientConfig.User).To(Equal("semver"))
+		})
+		It("requires a host and path", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverSFTP, Host: "files.example.com"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("HTTP", func() {
+		It("returns an http driver", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:      models.DriverHTTP,
+				URI:         "https://nexus.example.com/repository/versions/some-app",
+				BearerToken: "some-token",
+				Headers:     map[string]string{"X-Team": "release"},
+			})
+			Expect(err).To(BeNil())
+			httpDriver, ok := aDriver.(*driver.HTTPDriver)
+			Expect(ok).To(BeTrue())
+			Expect(httpDriver.URL).To(Equal("https://nexus.example.com/repository/versions/some-app"))
+			Expect(httpDriver.BearerToken).To(Equal("some-token"))
+			Expect(httpDriver.Headers).To(Equal(map[string]string{"X-Team": "release"}))
+			Expect(httpDriver.Client).ToNot(BeNil())
+		})
+		It("requires a uri", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverHTTP})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("DynamoDB", func() {
+		It("returns a dynamodb driver with default attribute names", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:        models.DriverDynamoDB,
+				DynamoDBTable: "releases",
+				Key:           "some-app",
+				Endpoint:      "https://dynamodb.example.com",
+			})
+			Expect(err).To(BeNil())
+			dynamoDBDriver, ok := aDriver.(*driver.DynamoDBDriver)
+			Expect(ok).To(BeTrue())
+			Expect(dynamoDBDriver.TableName).To(Equal("releases"))
+			Expect(dynamoDBDriver.KeyAttribute).To(Equal("id"))
+			Expect(dynamoDBDriver.Key).To(Equal("some-app"))
+			Expect(dynamoDBDriver.VersionAttribute).To(Equal("version"))
+			svc, ok := dynamoDBDriver.Svc.(*dynamodb.Client)
+			Expect(ok).To(BeTrue())
+			Expect(svc.Options().BaseEndpoint).To(Equal(aws.String("https://dynamodb.example.com")))
+		})
+		It("requires a table and key", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverDynamoDB, Key: "some-app"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("SSM", func() {
+		It("returns an ssm driver using the given endpoint", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:     models.DriverSSM,
+				Key:        "/ci/version",
+				RegionName: "eu-west-1",
+				Endpoint:   "ssm.example.com",
+				DisableSSL: true,
+			})
+			Expect(err).To(BeNil())
+			ssmDriver, ok := aDriver.(*driver.SSMDriver)
+			Expect(ok).To(BeTrue())
+			Expect(ssmDriver.ParameterName).To(Equal("/ci/version"))
+			Expect(ssmDriver.LockTimeout).To(Equal(time.Minute))
+			Expect(ssmDriver.StaleLockAge).To(Equal(10 * time.Minute))
+			svc, ok := ssmDriver.Svc.(*ssm.Client)
+			Expect(ok).To(BeTrue())
+			Expect(svc.Options().Region).To(Equal("eu-west-1"))
+			Expect(svc.Options().BaseEndpoint).To(Equal(aws.String("http://ssm.example.com")))
+		})
+		It("requires a key", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverSSM})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Kubernetes", func() {
+		It("returns a kubernetes driver using the kubeconfig", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:    models.DriverKubernetes,
+				Namespace: "ci",
+				ConfigMap: "release",
+				Kubeconfig: `
+apiVersion: v1
+kind: Config
+clusters:
+- name: some-cluster
+  cluster:
+    server: https://k8s.example.com:6443
+users:
+- name: some-user
+  user:
+    token: some-token
+contexts:
+- name: some-context
+  context:
+    cluster: some-cluster
+    user: some-user
+current-context: some-context
+`,
+			})
+			Expect(err).To(BeNil())
+			kubernetesDriver, ok := aDriver.(*driver.KubernetesDriver)
+			Expect(ok).To(BeTrue())
+			Expect(kubernetesDriver.Namespace).To(Equal("ci"))
+			Expect(kubernetesDriver.ConfigMapName).To(Equal("release"))
+			Expect(kubernetesDriver.Key).To(Equal("version"))
+		})
+		It("requires a namespace and config map", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverKubernetes, ConfigMap: "release"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("SQL", func() {
+		It("returns a sql driver with default table names", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:       models.DriverSQL,
+				DatabaseType: "postgres",
+				DatabaseURL:  "postgres://semver@db.example.com/releases",
+				Key:          "some-version",
+			})
+			Expect(err).To(BeNil())
+			sqlDriver, ok := aDriver.(*driver.SQLDriver)
+			Expect(ok).To(BeTrue())
+			Expect(sqlDriver.Name).To(Equal("some-version"))
+			Expect(sqlDriver.VersionsTable).To(Equal("semver_versions"))
+			Expect(sqlDriver.HistoryTable).To(Equal("semver_history"))
+		})
+	})
+
+	Context("Redis", func() {
+		It("returns a redis driver for the key", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:        models.DriverRedis,
+				RedisAddress:  "redis.example.com:6379",
+				RedisDatabase: 3,
+				RedisTLS:      true,
+				Key:           "some-version",
+			})
+			Expect(err).To(BeNil())
+			redisDriver, ok := aDriver.(*driver.RedisDriver)
+			Expect(ok).To(BeTrue())
+			Expect(redisDriver.Key).To(Equal("some-version"))
+			Expect(redisDriver.Client.Options().DB).To(Equal(3))
+			Expect(redisDriver.Client.Options().TLSConfig).NotTo(BeNil())
+		})
+		It("requires an address", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverRedis, Key: "some-version"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Etcd", func() {
+		It("returns an etcd driver for the key", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:        models.DriverEtcd,
+				EtcdEndpoints: []string{"http://etcd.example.com:2379"},
+				Key:           "/versions/some-version",
+			})
+			Expect(err).To(BeNil())
+			etcdDriver, ok := aDriver.(*driver.EtcdDriver)
+			Expect(ok).To(BeTrue())
+			Expect(etcdDriver.Key).To(Equal("/versions/some-version"))
+			Expect(etcdDriver.Client.Endpoints()).To(Equal([]string{"http://etcd.example.com:2379"}))
+			Expect(etcdDriver.RequestTimeout).To(Equal(30 * time.Second))
+		})
+		It("requires endpoints", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverEtcd, Key: "/versions/some-version"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Consul", func() {
+		It("returns a consul driver for the key", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:        models.DriverConsul,
+				ConsulAddress: "consul.example.com:8500",
+				Key:           "releases/some-version",
+			})
+			Expect(err).To(BeNil())
+			consulDriver, ok := aDriver.(*driver.ConsulDriver)
+			Expect(ok).To(BeTrue())
+			Expect(consulDriver.Key).To(Equal("releases/some-version"))
+		})
+		It("requires a key", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverConsul})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Vault", func() {
+		It("defaults the mount and field", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:       models.DriverVault,
+				VaultAddress: "https://vault.example.com:8200",
+				VaultPath:    "some/release",
+			})
+			Expect(err).To(BeNil())
+			vaultDriver, ok := aDriver.(*driver.VaultDriver)
+			Expect(ok).To(BeTrue())
+			Expect(vaultDriver.Mount).To(Equal("secret"))
+			Expect(vaultDriver.Path).To(Equal("some/release"))
+			Expect(vaultDriver.Field).To(Equal("version"))
+			Expect(vaultDriver.Client.Address()).To(Equal("https://vault.example.com:8200"))
+		})
+		It("requires a path", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverVault})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Azure", func() {
+		It("returns an azure driver for the container and blob", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:            models.DriverAzure,
+				StorageAccount:    "account",
+				StorageAccountKey: "a2V5",
+				Container:         "versions",
+				Key:               "some-version",
+			})
+			Expect(err).To(BeNil())
+			azureDriver, ok := aDriver.(*driver.AzureDriver)
+			Expect(ok).To(BeTrue())
+			Expect(azureDriver.ContainerName).To(Equal("versions"))
+			Expect(azureDriver.BlobName).To(Equal("some-version"))
+			servicer, ok := azureDriver.Servicer.(*driver.AzureBlobServicer)
+			Expect(ok).To(BeTrue())
+			Expect(servicer.Client.URL()).To(Equal("https://account.blob.core.windows.net/"))
+		})
+	})
+
+	Context("File", func() {
+		It("returns a file driver", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver: models.DriverFile,
+				Path:   "/some/version",
+			})
+			Expect(err).To(BeNil())
+			fileDriver, ok := aDriver.(*driver.FileDriver)
+			Expect(ok).To(BeTrue())
+			Expect(fileDriver.Path).To(Equal("/some/version"))
+		})
+		It("requires a path", func() {
+			_, err := driver.FromSource(models.Source{Driver: models.DriverFile})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("Git tags", func() {
+		It("returns a git tags driver", func() {
+			aDriver, err := driver.FromSource(models.Source{
+				Driver:    models.DriverGitTags,
+				URI:       "git@example.com:some/repo.git",
+				Branch:    "main",
+				TagPrefix: "v",
+			})
+			Expect(err).To(BeNil())
+			gitTagsDriver, ok := aDriver.(*driver.GitTagsDriver)
+			Expect(ok).To(BeTrue())
+			Expect(gitTagsDriver.Branch).To(Equal("main"))
+			Expect(gitTagsDriver.TagPrefix).To(Equal("v"))
+		})
+	})
+
 	Context("GCS", func() {
 		var src models.Source
 		BeforeEach(func() {
@@ -0,0 +1,137 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+)
+
+type DynamoDBServicer interface {
+	GetItem(context.Context, *dynamodb.GetItemInput, ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	UpdateItem(context.Context, *dynamodb.UpdateItemInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// DynamoDBDriver stores the version in a string attribute of an item. Other
+// attributes of the item are left as they are.
+type DynamoDBDriver struct {
+	InitialVersion semver.Version
+
+	Svc              DynamoDBServicer
+	TableName        string
+	KeyAttribute     string
+	Key              string
+	VersionAttribute string
+}
+
+func (d *DynamoDBDriver) Bump(b version.Bump) (semver.Version, error) {
+	read := func() (semver.Version, *string, error) {
+		v, value, err := d.readVersion()
+		if err == nil && value == nil {
+			v = d.InitialVersion
+		}
+
+		return v, value, err
+	}
+
+	// only write if the attribute still holds the value that was read,
+	// otherwise re-read and re-apply the bump
+	return retryOnConflict(b, read, d.writeVersion, func(err error) bool {
+		var conditionFailed *types.ConditionalCheckFailedException
+		return errors.As(err, &conditionFailed)
+	})
+}
+
+func (d *DynamoDBDriver) Set(newVersion semver.Version) error {
+	_, err := d.Svc.UpdateItem(context.TODO(), d.updateItemInput(newVersion))
+	return err
+}
+
+func (d *DynamoDBDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	v, value, err := d.readVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		if cursor == nil {
+			return []semver.Version{d.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	}
+
+	// the item keeps no history, so only the current version can be reported
+	return []semver.Version{v}, nil
+}
+
+func (d *DynamoDBDriver) key() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		d.KeyAttribute: &types.AttributeValueMemberS{Value: d.Key},
+	}
+}
+
+// readVersion returns the version in the item along with the attribute value
+// it was parsed from. The value is nil when the item or its version attribute
+// does not exist.
+func (d *DynamoDBDriver) readVersion() (semver.Version, *string, error) {
+	resp, err := d.Svc.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName:      aws.String(d.TableName),
+		Key:            d.key(),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return semver.Version{}, nil, err
+	}
+
+	attribute, found := resp.Item[d.VersionAttribute]
+	if !found {
+		return semver.Version{}, nil, nil
+	}
+
+	value, ok := attribute.(*types.AttributeValueMemberS)
+	if !ok {
+		return semver.Version{}, nil, fmt.Errorf("attribute %s of item %s is not a string", d.VersionAttribute, d.Key)
+	}
+
+	v, err := semver.Parse(strings.TrimSpace(value.Value))
+	if err != nil {
+		return semver.Version{}, nil, fmt.Errorf("parsing number in attribute %s of item %s: %s", d.VersionAttribute, d.Key, err)
+	}
+
+	return v, &value.Value, nil
+}
+
+// writeVersion sets the version attribute only if it still holds the given
+// value, or, when value is nil, only if it does not exist yet.
+func (d *DynamoDBDriver) writeVersion(newVersion semver.Version, value *string) error {
+	params := d.updateItemInput(newVersion)
+	if value != nil {
+		params.ConditionExpression = aws.String("#version = :current")
+		params.ExpressionAttributeValues[":current"] = &types.AttributeValueMemberS{Value: *value}
+	} else {
+		params.ConditionExpression = aws.String("attribute_not_exists(#version)")
+	}
+
+	_, err := d.Svc.UpdateItem(context.TODO(), params)
+	return err
+}
+
+// updateItemInput sets the version attribute, creating the item if it does
+// not exist.
+func (d *DynamoDBDriver) updateItemInput(newVersion semver.Version) *dynamodb.UpdateItemInput {
+	return &dynamodb.UpdateItemInput{
+		TableName:                aws.String(d.TableName),
+		Key:                      d.key(),
+		UpdateExpression:         aws.String("SET #version = :new"),
+		ExpressionAttributeNames: map[string]string{"#version": d.VersionAttribute},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":new": &types.AttributeValueMemberS{Value: newVersion.String()},
+		},
+	}
+}
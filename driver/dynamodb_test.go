@@ -0,0 +1,186 @@
+package driver_test
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DynamoDB Driver", func() {
+	var (
+		table *fakeDynamoDBTable
+		d     *driver.DynamoDBDriver
+	)
+
+	BeforeEach(func() {
+		table = &fakeDynamoDBTable{}
+
+		d = &driver.DynamoDBDriver{
+			InitialVersion:   semver.Version{Major: 1},
+			Svc:              table,
+			TableName:        "releases",
+			KeyAttribute:     "id",
+			Key:              "some-app",
+			VersionAttribute: "version",
+		}
+	})
+
+	Describe("Check", func() {
+		It("returns the initial version when the item does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the initial version when the item has no version attribute", func() {
+			table.item = map[string]string{"id": "some-app", "owner": "release-team"}
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the version in the item", func() {
+			table.item = map[string]string{"id": "some-app", "version": "2.3.4"}
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("returns an error when the attribute does not contain a version", func() {
+			table.item = map[string]string{"id": "some-app", "version": "bogus"}
+
+			_, err := d.Check(nil)
+			Expect(err).To(MatchError(ContainSubstring("parsing number in attribute version of item some-app")))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("creates the item when it does not exist", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(table.item).To(Equal(map[string]string{"id": "some-app", "version": "1.1.0"}))
+		})
+
+		It("keeps the other attributes of the item", func() {
+			table.item = map[string]string{"id": "some-app", "owner": "release-team", "version": "1.2.3\n"}
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(table.item).To(Equal(map[string]string{"id": "some-app", "owner": "release-team", "version": "1.2.4"}))
+		})
+	})
+
+	Describe("Set", func() {
+		It("creates the item when it does not exist", func() {
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(table.item).To(Equal(map[string]string{"id": "some-app", "version": "5.0.0"}))
+		})
+
+		It("overwrites the version regardless of its value", func() {
+			table.item = map[string]string{"id": "some-app", "version": "1.2.3"}
+			table.concurrentWrites = []string{"1.5.0"}
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(table.item["version"]).To(Equal("5.0.0"))
+		})
+	})
+})
+
+// fakeDynamoDBTable holds a single item of string attributes and understands
+// the update and condition expressions used by the driver. Each entry of
+// concurrentWrites is written to the version attribute by "another writer"
+// right before an UpdateItem call is handled.
+type fakeDynamoDBTable struct {
+	item             map[string]string
+	concurrentWrites []string
+}
+
+func (t *fakeDynamoDBTable) GetItem(ctx context.Context, p *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	Expect(aws.ToString(p.TableName)).To(Equal("releases"))
+	Expect(p.Key).To(Equal(map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "some-app"}}))
+	Expect(aws.ToBool(p.ConsistentRead)).To(BeTrue())
+
+	if t.item == nil {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+
+	item := map[string]types.AttributeValue{}
+	for name, value := range t.item {
+		item[name] = &types.AttributeValueMemberS{Value: value}
+	}
+
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (t *fakeDynamoDBTable) UpdateItem(ctx context.Context, p *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	Expect(aws.ToString(p.TableName)).To(Equal("releases"))
+	Expect(p.Key).To(Equal(map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "some-app"}}))
+	Expect(aws.ToString(p.UpdateExpression)).To(Equal("SET #version = :new"))
+	Expect(p.ExpressionAttributeNames).To(Equal(map[string]string{"#version": "version"}))
+
+	if len(t.concurrentWrites) > 0 {
+		t.put(t.concurrentWrites[0])
+		t.concurrentWrites = t.concurrentWrites[1:]
+	}
+
+	current, exists := t.item["version"]
+
+	switch aws.ToString(p.ConditionExpression) {
+	case "":
+	case "attribute_not_exists(#version)":
+		if exists {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		}
+	case "#version = :current":
+		expected := p.ExpressionAttributeValues[":current"].(*types.AttributeValueMemberS).Value
+		if !exists || current != expected {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		}
+	default:
+		Fail("unexpected condition expression " + aws.ToString(p.ConditionExpression))
+	}
+
+	t.put(p.ExpressionAttributeValues[":new"].(*types.AttributeValueMemberS).Value)
+
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (t *fakeDynamoDBTable) put(version string) {
+	if t.item == nil {
+		t.item = map[string]string{"id": "some-app"}
+	}
+
+	t.item["version"] = version
+}
+
+func dynamoDBCASSubject() casSubject {
+	table := &fakeDynamoDBTable{}
+
+	return casSubject{
+		driver: &driver.DynamoDBDriver{
+			InitialVersion:   semver.Version{Major: 1},
+			Svc:              table,
+			TableName:        "releases",
+			KeyAttribute:     "id",
+			Key:              "some-app",
+			VersionAttribute: "version",
+		},
+		store:   func(v string) { table.item = map[string]string{"id": "some-app", "version": v} },
+		current: func() string { return table.item["version"] },
+		race:    func(versions ...string) { table.concurrentWrites = versions },
+	}
+}
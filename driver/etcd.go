@@ -0,0 +1,198 @@
+package driver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+)
+
+type EtcdDriver struct {
+	InitialVersion semver.Version
+
+	Client *clientv3.Client
+	Key    string
+
+	// RequestTimeout bounds every request to the cluster, as the client
+	// keeps retrying an unreachable cluster for as long as it is allowed to.
+	RequestTimeout time.Duration
+}
+
+func NewEtcdClient(source models.Source) (*clientv3.Client, error) {
+	config := clientv3.Config{
+		Endpoints:   source.EtcdEndpoints,
+		Username:    source.EtcdUsername,
+		Password:    source.EtcdPassword,
+		DialTimeout: 10 * time.Second,
+	}
+
+	if slices.ContainsFunc(source.EtcdEndpoints, func(endpoint string) bool {
+		return strings.HasPrefix(endpoint, "https://")
+	}) {
+		config.TLS = &tls.Config{InsecureSkipVerify: source.SkipSSLVerification}
+	}
+
+	return clientv3.New(config)
+}
+
+func (d *EtcdDriver) Bump(b version.Bump) (semver.Version, error) {
+	read := func() (semver.Version, int64, error) {
+		kv, err := d.get()
+		if err != nil {
+			return semver.Version{}, 0, err
+		}
+
+		// a key that does not exist has a mod revision of 0
+		if kv == nil {
+			return d.InitialVersion, 0, nil
+		}
+
+		v, err := d.parseVersion(kv)
+		return v, kv.ModRevision, err
+	}
+
+	return retryOnConflict(b, read, func(newVersion semver.Version, modRevision int64) error {
+		ctx, cancel := d.requestContext()
+		defer cancel()
+
+		resp, err := d.Client.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(d.Key), "=", modRevision)).
+			Then(clientv3.OpPut(d.Key, newVersion.String())).
+			Commit()
+		if err != nil {
+			return err
+		}
+
+		if !resp.Succeeded {
+			return fmt.Errorf("key %s was %w", d.Key, errModifiedConcurrently)
+		}
+
+		return nil
+	}, isModifiedConcurrently)
+}
+
+func (d *EtcdDriver) Set(v semver.Version) error {
+	ctx, cancel := d.requestContext()
+	defer cancel()
+
+	_, err := d.Client.Put(ctx, d.Key, v.String())
+	return err
+}
+
+func (d *EtcdDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	kv, err := d.get()
+	if err != nil {
+		return nil, err
+	}
+
+	if kv == nil {
+		if cursor == nil {
+			return []semver.Version{d.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	}
+
+	v, err := d.parseVersion(kv)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle a "fly check-resource --from <cursor>" to bring back old versions
+	if cursor != nil {
+		return d.getOldVersions(cursor, v, kv.ModRevision)
+	}
+
+	return []semver.Version{v}, nil
+}
+
+// getOldVersions() goes back through the key's revisions to find all versions
+// newer than the cursor. The loop ends when we find a version older than the
+// cursor, reach the key's creation or reach the compacted part of history.
+func (d *EtcdDriver) getOldVersions(cursor *semver.Version, currentVersion semver.Version, modRevision int64) ([]semver.Version, error) {
+	// Supplied cursor version is newer or equal to current, so we do not need to go back in history
+	if cursor.GTE(currentVersion) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	oldVersions := []semver.Version{currentVersion}
+	for {
+		// the key as of just before the revision that last modified it
+		ctx, cancel := d.requestContext()
+		resp, err := d.Client.Get(ctx, d.Key, clientv3.WithRev(modRevision-1))
+		cancel()
+		if errors.Is(err, rpctypes.ErrCompacted) {
+			fmt.Fprintf(os.Stderr, "history of key %s before revision %d has been compacted\n", d.Key, modRevision)
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// We have reached the beginning of the key's history
+		if len(resp.Kvs) == 0 {
+			break
+		}
+
+		kv := resp.Kvs[0]
+
+		previousVersion, err := d.parseVersion(kv)
+		if err != nil {
+			return nil, fmt.Errorf("revision %d: %w", kv.ModRevision, err)
+		}
+
+		// If cursor is newer than previous version, we've found all versions between cursor and current
+		if cursor.GT(previousVersion) {
+			break
+		}
+
+		// Cursor is older or equal to previous version, so include previous version and continue
+		oldVersions = append(oldVersions, previousVersion)
+		modRevision = kv.ModRevision
+	}
+
+	slices.Reverse(oldVersions)
+	return oldVersions, nil
+}
+
+func (d *EtcdDriver) requestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), d.RequestTimeout)
+}
+
+// get returns the key, or nil if it does not exist.
+func (d *EtcdDriver) get() (*mvccpb.KeyValue, error) {
+	ctx, cancel := d.requestContext()
+	defer cancel()
+
+	resp, err := d.Client.Get(ctx, d.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, nil
+	}
+
+	return resp.Kvs[0], nil
+}
+
+func (d *EtcdDriver) parseVersion(kv *mvccpb.KeyValue) (semver.Version, error) {
+	v, err := semver.Parse(strings.TrimSpace(string(kv.Value)))
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("parsing number in key %s: %s", d.Key, err)
+	}
+
+	return v, nil
+}
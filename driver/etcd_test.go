@@ -0,0 +1,210 @@
+package driver_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/blang/semver"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Etcd Driver", Ordered, func() {
+	var (
+		client *clientv3.Client
+		keys   int
+		key    string
+		d      *driver.EtcdDriver
+	)
+
+	BeforeAll(func() {
+		cfg := embed.NewConfig()
+		cfg.Dir = GinkgoT().TempDir()
+		cfg.LogLevel = "error"
+
+		clientURL, err := url.Parse("unix://" + cfg.Dir + "/client.sock")
+		Expect(err).NotTo(HaveOccurred())
+		peerURL, err := url.Parse("unix://" + cfg.Dir + "/peer.sock")
+		Expect(err).NotTo(HaveOccurred())
+
+		cfg.ListenClientUrls = []url.URL{*clientURL}
+		cfg.AdvertiseClientUrls = []url.URL{*clientURL}
+		cfg.ListenPeerUrls = []url.URL{*peerURL}
+		cfg.AdvertisePeerUrls = []url.URL{*peerURL}
+		cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+		server, err := embed.StartEtcd(cfg)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(server.Close)
+
+		Eventually(server.Server.ReadyNotify()).WithTimeout(30 * time.Second).Should(BeClosed())
+
+		client, err = driver.NewEtcdClient(models.Source{
+			EtcdEndpoints: []string{clientURL.String()},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(client.Close)
+	})
+
+	BeforeEach(func() {
+		keys++
+		key = fmt.Sprintf("/versions/%d", keys)
+
+		d = &driver.EtcdDriver{
+			InitialVersion: semver.Version{Major: 1},
+			Client:         client,
+			Key:            key,
+			RequestTimeout: 10 * time.Second,
+		}
+	})
+
+	put := func(value string) int64 {
+		resp, err := client.Put(context.Background(), key, value)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.Header.Revision
+	}
+
+	current := func() string {
+		resp, err := client.Get(context.Background(), key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Kvs).To(HaveLen(1))
+		return string(resp.Kvs[0].Value)
+	}
+
+	Describe("Check", func() {
+		It("returns the initial version when the key does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the version in the key", func() {
+			put("2.3.4")
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("returns every version from the cursor onwards, oldest first", func() {
+			for _, v := range []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"} {
+				put(v)
+
+				// writes to other keys interleave with the key's revisions
+				_, err := client.Put(context.Background(), key+"-other", v)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			cursor := semver.MustParse("1.1.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.1.0"),
+				semver.MustParse("1.2.0"),
+				semver.MustParse("2.0.0"),
+			}))
+		})
+
+		It("does not go back past the key being recreated", func() {
+			put("1.0.0")
+			_, err := client.Delete(context.Background(), key)
+			Expect(err).NotTo(HaveOccurred())
+			put("1.1.0")
+			put("1.2.0")
+
+			cursor := semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.1.0"),
+				semver.MustParse("1.2.0"),
+			}))
+		})
+
+		It("returns what is left of the history after compaction", func() {
+			put("1.0.0")
+			compactAt := put("1.1.0")
+			put("1.2.0")
+
+			_, err := client.Compact(context.Background(), compactAt)
+			Expect(err).NotTo(HaveOccurred())
+
+			cursor := semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.1.0"),
+				semver.MustParse("1.2.0"),
+			}))
+		})
+
+		It("returns the current version when the cursor is not older", func() {
+			put("1.0.0")
+			put("2.0.0")
+
+			cursor := semver.MustParse("3.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("bumps the initial version when the key does not exist", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(current()).To(Equal("1.1.0"))
+		})
+
+		It("does not lose bumps made concurrently", func() {
+			put("1.0.0")
+
+			results := make(chan error, 2)
+			for range 2 {
+				go func() {
+					_, err := d.Bump(version.PatchBump{})
+					results <- err
+				}()
+			}
+
+			Expect(<-results).NotTo(HaveOccurred())
+			Expect(<-results).NotTo(HaveOccurred())
+			Expect(current()).To(Equal("1.0.2"))
+		})
+	})
+
+	Describe("Set", func() {
+		It("writes the key", func() {
+			put("1.2.3")
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current()).To(Equal("5.0.0"))
+		})
+	})
+
+	It("gives up on an unreachable cluster after the request timeout", func() {
+		unreachable, err := driver.NewEtcdClient(models.Source{
+			EtcdEndpoints: []string{"unix://" + GinkgoT().TempDir() + "/missing.sock"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(unreachable.Close)
+
+		d.Client = unreachable
+		d.RequestTimeout = time.Second
+
+		start := time.Now()
+		_, err = d.Check(nil)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	})
+})
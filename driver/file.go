@@ -0,0 +1,149 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/version"
+)
+
+// FileDriver stores the version in a file on a locally mounted path. Writers
+// serialize on an advisory lock held on a sibling ".lock" file, and the
+// version file is only ever replaced by renaming a complete copy over it.
+type FileDriver struct {
+	InitialVersion semver.Version
+
+	Path string
+}
+
+func (driver *FileDriver) Bump(bump version.Bump) (semver.Version, error) {
+	unlock, err := driver.lock()
+	if err != nil {
+		return semver.Version{}, err
+	}
+	defer unlock()
+
+	currentVersion, exists, err := driver.readVersion()
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	if !exists {
+		currentVersion = driver.InitialVersion
+	}
+
+	newVersion, err := version.Apply(bump, currentVersion)
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	err = driver.writeVersion(newVersion)
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	return newVersion, nil
+}
+
+func (driver *FileDriver) Set(newVersion semver.Version) error {
+	unlock, err := driver.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return driver.writeVersion(newVersion)
+}
+
+func (driver *FileDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	currentVersion, exists, err := driver.readVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return []semver.Version{driver.InitialVersion}, nil
+	}
+
+	// the file keeps no history, so only the current version can be reported
+	return []semver.Version{currentVersion}, nil
+}
+
+// lock takes an exclusive advisory lock, blocking until any other writer has
+// released it. The lock is held on a separate file because the version file
+// itself is replaced on every write.
+func (driver *FileDriver) lock() (func(), error) {
+	lockFile, err := os.OpenFile(driver.Path+".lock", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		lockFile.Close()
+		return nil, fmt.Errorf("locking %s: %w", lockFile.Name(), err)
+	}
+
+	return func() {
+		syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+		lockFile.Close()
+	}, nil
+}
+
+func (driver *FileDriver) readVersion() (semver.Version, bool, error) {
+	contents, err := os.ReadFile(driver.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return semver.Version{}, false, nil
+	}
+
+	if err != nil {
+		return semver.Version{}, false, err
+	}
+
+	currentVersion, err := semver.Parse(strings.TrimSpace(string(contents)))
+	if err != nil {
+		return semver.Version{}, false, fmt.Errorf("parsing number in %s: %s", driver.Path, err)
+	}
+
+	return currentVersion, true, nil
+}
+
+// writeVersion writes the version to a temporary file next to the version
+// file and renames it into place, so readers never see a partial write.
+func (driver *FileDriver) writeVersion(newVersion semver.Version) error {
+	tmp, err := os.CreateTemp(filepath.Dir(driver.Path), "."+filepath.Base(driver.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.WriteString(newVersion.String() + "\n")
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), driver.Path)
+}
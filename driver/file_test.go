@@ -0,0 +1,113 @@
+package driver_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File Driver", func() {
+	var (
+		path string
+		d    *driver.FileDriver
+	)
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "version")
+
+		d = &driver.FileDriver{
+			InitialVersion: semver.Version{Major: 1},
+			Path:           path,
+		}
+	})
+
+	Describe("Check", func() {
+		It("returns the initial version when the file does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the version in the file", func() {
+			Expect(os.WriteFile(path, []byte("2.3.4\n"), 0644)).To(Succeed())
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("returns an error when the file does not contain a version", func() {
+			Expect(os.WriteFile(path, []byte("bogus"), 0644)).To(Succeed())
+
+			_, err := d.Check(nil)
+			Expect(err).To(MatchError(ContainSubstring("parsing number in")))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("bumps the initial version when the file does not exist", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(os.ReadFile(path)).To(Equal([]byte("1.1.0\n")))
+		})
+
+		It("does not lose bumps made concurrently", func() {
+			Expect(os.WriteFile(path, []byte("1.0.0\n"), 0644)).To(Succeed())
+
+			var wg sync.WaitGroup
+			for range 20 {
+				wg.Go(func() {
+					defer GinkgoRecover()
+
+					_, err := d.Bump(version.PatchBump{})
+					Expect(err).NotTo(HaveOccurred())
+				})
+			}
+			wg.Wait()
+
+			Expect(os.ReadFile(path)).To(Equal([]byte("1.0.20\n")))
+		})
+
+		It("refuses a checked bump without writing", func() {
+			Expect(os.WriteFile(path, []byte("1.2.3\n"), 0644)).To(Succeed())
+
+			_, err := d.Bump(version.PromoteBump{Channels: version.PreReleaseChannels{"rc"}})
+			Expect(err).To(MatchError(ContainSubstring("already a final version")))
+			Expect(os.ReadFile(path)).To(Equal([]byte("1.2.3\n")))
+		})
+	})
+
+	Describe("Set", func() {
+		It("replaces the file without leaving temporary files behind", func() {
+			Expect(os.WriteFile(path, []byte("1.2.3\n"), 0644)).To(Succeed())
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.ReadFile(path)).To(Equal([]byte("5.0.0\n")))
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			Expect(err).NotTo(HaveOccurred())
+
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			Expect(names).To(ConsistOf("version", "version.lock"))
+		})
+
+		It("returns an error when the directory does not exist", func() {
+			d.Path = filepath.Join(filepath.Dir(path), "missing", "version")
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -5,11 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/blang/semver"
 	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 
 	"github.com/concourse/semver-resource/version"
@@ -21,26 +27,37 @@ type GCSDriver struct {
 	Servicer   IOServicer
 	BucketName string
 	Key        string
+
+	// RetryBackoff is how long Bump waits before retrying after losing a race
+	// with another writer. It doubles on every attempt, up to
+	// gcsMaxRetryBackoff.
+	RetryBackoff time.Duration
 }
 
-func (d *GCSDriver) Bump(b version.Bump) (semver.Version, error) {
-	versions, err := d.Check(nil)
+const gcsMaxRetryBackoff = 2 * time.Second
 
-	if err != nil {
-		return semver.Version{}, err
-	}
+func (d *GCSDriver) Bump(b version.Bump) (semver.Version, error) {
+	var backoff time.Duration
 
-	if len(versions) == 0 {
-		return semver.Version{}, nil
-	}
+	read := func() (semver.Version, int64, error) {
+		// GCS limits how often an object can be written, so retries after
+		// losing a race back off
+		if backoff > 0 {
+			time.Sleep(backoff)
+			backoff = min(backoff*2, gcsMaxRetryBackoff)
+		} else {
+			backoff = d.RetryBackoff
+		}
 
-	newVersion := b.Apply(versions[0])
-	err = d.Set(newVersion)
+		v, generation, err := d.readVersion()
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return d.InitialVersion, 0, nil
+		}
 
-	if err != nil {
-		return semver.Version{}, err
+		return v, generation, err
 	}
-	return newVersion, nil
+
+	return retryOnConflict(b, read, d.writeVersion, isGCSPreconditionFailed)
 }
 
 func (d *GCSDriver) Set(v semver.Version) error {
@@ -56,8 +73,7 @@ func (d *GCSDriver) Set(v semver.Version) error {
 }
 
 func (d *GCSDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
-	r, err := d.Servicer.GetObject(d.BucketName, d.Key)
-
+	v, generation, err := d.readVersion()
 	if errors.Is(err, storage.ErrObjectNotExist) {
 		if cursor == nil {
 			return []semver.Version{d.InitialVersion}, nil
@@ -66,29 +82,136 @@ func (d *GCSDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
 	} else if err != nil {
 		return nil, err
 	}
+
+	// Handle a "fly check-resource --from <cursor>" to bring back old versions
+	if cursor != nil {
+		return d.getOldVersions(cursor, v, generation)
+	}
+
+	return []semver.Version{v}, nil
+}
+
+// getOldVersions() goes back through the object's noncurrent generations to
+// find all versions newer than the cursor. Without object versioning on the
+// bucket there are no older generations, so only the current version is
+// returned.
+func (d *GCSDriver) getOldVersions(cursor *semver.Version, currentVersion semver.Version, currentGeneration int64) ([]semver.Version, error) {
+	// Supplied cursor version is newer or equal to current, so we do not need to go back in history
+	if cursor.GTE(currentVersion) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	generations, err := d.Servicer.ListGenerations(d.BucketName, d.Key)
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+			fmt.Fprintf(os.Stderr, "not permitted to list object generations, skipping version history: %s\n", err)
+			return []semver.Version{currentVersion}, nil
+		}
+
+		return nil, fmt.Errorf("listing object generations: %w", err)
+	}
+
+	oldVersions := []semver.Version{currentVersion}
+	for _, generation := range generations {
+		// skip the generation we started from and anything written since
+		if generation >= currentGeneration {
+			continue
+		}
+
+		r, err := d.Servicer.GetObjectGeneration(d.BucketName, d.Key, generation)
+		if err != nil {
+			return nil, err
+		}
+
+		previousVersion, err := parseGCSVersion(r)
+		if err != nil {
+			return nil, fmt.Errorf("generation %d: %w", generation, err)
+		}
+
+		// If cursor is newer than previous version, we've found all versions between cursor and current
+		if cursor.GT(previousVersion) {
+			break
+		}
+
+		// Cursor is older or equal to previous version, so include previous version and continue
+		oldVersions = append(oldVersions, previousVersion)
+	}
+
+	slices.Reverse(oldVersions)
+	return oldVersions, nil
+}
+
+// readVersion returns the current version and the generation of the object
+// it was read from.
+func (d *GCSDriver) readVersion() (semver.Version, int64, error) {
+	r, generation, err := d.Servicer.GetObject(d.BucketName, d.Key)
+	if err != nil {
+		return semver.Version{}, 0, err
+	}
+
+	v, err := parseGCSVersion(r)
+	if err != nil {
+		return semver.Version{}, 0, err
+	}
+
+	return v, generation, nil
+}
+
+func parseGCSVersion(r io.ReadCloser) (semver.Version, error) {
 	defer r.Close()
 
 	b, err := io.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return semver.Version{}, err
 	}
 
 	v, err := semver.Parse(strings.TrimSpace(string(b)))
 	if err != nil {
-		return nil, fmt.Errorf("parsing number in bucket: %s", err)
+		return semver.Version{}, fmt.Errorf("parsing number in bucket: %s", err)
 	}
 
-	return []semver.Version{v}, nil
+	return v, nil
+}
+
+func (d *GCSDriver) writeVersion(v semver.Version, generation int64) error {
+	w, err := d.Servicer.PutObjectIfGeneration(d.BucketName, d.Key, generation)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte(v.String()))
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func isGCSPreconditionFailed(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed
 }
 
 type IOServicer interface {
-	GetObject(bucketName, objectName string) (io.ReadCloser, error)
+	// GetObject returns the contents of the object along with its generation.
+	GetObject(bucketName, objectName string) (io.ReadCloser, int64, error)
 	PutObject(bucketName, objectName string) (io.WriteCloser, error)
+	// GetObjectGeneration returns the contents of a specific generation of
+	// the object, which may no longer be the live one.
+	GetObjectGeneration(bucketName, objectName string, generation int64) (io.ReadCloser, error)
+	// ListGenerations returns every stored generation of the object, newest
+	// first.
+	ListGenerations(bucketName, objectName string) ([]int64, error)
+	// PutObjectIfGeneration only writes the object if its generation still
+	// matches; a generation of 0 requires that the object does not exist.
+	// A failed precondition is reported by Close on the returned writer.
+	PutObjectIfGeneration(bucketName, objectName string, generation int64) (io.WriteCloser, error)
 }
 
 type GCSIOServicer struct {
 	JSONCredentials string
 	Token           string
+
+	client *storage.Client
 }
 
 func (s *GCSIOServicer) authOption() (option.ClientOption, error) {
@@ -102,40 +225,121 @@ func (s *GCSIOServicer) authOption() (option.ClientOption, error) {
 	return option.WithAuthCredentialsJSON(option.ServiceAccount, []byte(s.JSONCredentials)), nil
 }
 
-func (s *GCSIOServicer) GetObject(bucketName, objectName string) (io.ReadCloser, error) {
+// storageClient returns the client shared by every request of the servicer,
+// creating it on first use.
+func (s *GCSIOServicer) storageClient() (*storage.Client, error) {
+	if s.client != nil {
+		return s.client, nil
+	}
+
 	authOpt, err := s.authOption()
 	if err != nil {
 		return nil, err
 	}
 
-	ctx := context.Background()
-	client, err := storage.NewClient(ctx, authOpt)
+	client, err := storage.NewClient(context.Background(), authOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	s.client = client
+	return client, nil
+}
+
+func (s *GCSIOServicer) object(bucketName, objectName string) (*storage.ObjectHandle, error) {
+	client, err := s.storageClient()
 	if err != nil {
 		return nil, err
 	}
 
 	bkt := client.Bucket(bucketName)
-	obj := bkt.Object(objectName)
+	return bkt.Object(objectName), nil
+}
+
+func (s *GCSIOServicer) GetObject(bucketName, objectName string) (io.ReadCloser, int64, error) {
+	obj, err := s.object(bucketName, objectName)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r, err := obj.NewReader(context.Background())
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return obj.NewReader(context.Background())
+	return r, r.Attrs.Generation, nil
 }
 
-func (s *GCSIOServicer) PutObject(bucketName, objectName string) (io.WriteCloser, error) {
-	authOpt, err := s.authOption()
+func (s *GCSIOServicer) GetObjectGeneration(bucketName, objectName string, generation int64) (io.ReadCloser, error) {
+	obj, err := s.object(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj.Generation(generation).NewReader(context.Background())
+}
+
+func (s *GCSIOServicer) ListGenerations(bucketName, objectName string) ([]int64, error) {
+	client, err := s.storageClient()
 	if err != nil {
 		return nil, err
 	}
 
 	ctx := context.Background()
-	client, err := storage.NewClient(ctx, authOpt)
+
+	query := &storage.Query{Prefix: objectName, Versions: true}
+	err = query.SetAttrSelection([]string{"Name", "Generation"})
 	if err != nil {
 		return nil, err
 	}
 
-	bkt := client.Bucket(bucketName)
-	obj := bkt.Object(objectName)
+	var generations []int64
+	it := client.Bucket(bucketName).Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if attrs.Name == objectName {
+			generations = append(generations, attrs.Generation)
+		}
+	}
+
+	slices.Sort(generations)
+	slices.Reverse(generations)
+	return generations, nil
+}
+
+func (s *GCSIOServicer) PutObject(bucketName, objectName string) (io.WriteCloser, error) {
+	obj, err := s.object(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	return newGCSWriter(obj), nil
+}
+
+func (s *GCSIOServicer) PutObjectIfGeneration(bucketName, objectName string, generation int64) (io.WriteCloser, error) {
+	obj, err := s.object(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+
+	if generation == 0 {
+		obj = obj.If(storage.Conditions{DoesNotExist: true})
+	} else {
+		obj = obj.If(storage.Conditions{GenerationMatch: generation})
+	}
+
+	return newGCSWriter(obj), nil
+}
 
+func newGCSWriter(obj *storage.ObjectHandle) *storage.Writer {
 	w := obj.NewWriter(context.Background())
 	w.CacheControl = "private"
-	return w, nil
+	return w
 }
@@ -3,12 +3,15 @@ package driver_test
 import (
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/blang/semver"
 	. "github.com/concourse/semver-resource/driver"
 	"github.com/concourse/semver-resource/version"
+	"google.golang.org/api/googleapi"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -75,6 +78,19 @@ var _ = Describe("GCS Driver", func() {
 				Expect(s.BucketName).To(Equal("fake-bucket"))
 				Expect(s.ObjectName).To(Equal("fake-object"))
 				Expect(s.Buf).To(gbytes.Say("0.0.1"))
+				Expect(s.PutGeneration).To(Equal(ptr(int64(0))))
+			})
+		})
+
+		Describe("when the object has a generation", func() {
+			It("writes only if the generation still matches", func() {
+				s.Body = "2.6.3"
+				s.Generation = 42
+
+				_, err := driver.Bump(version.PatchBump{})
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(s.PutGeneration).To(Equal(ptr(int64(42))))
 			})
 		})
 
@@ -214,6 +230,87 @@ var _ = Describe("GCS Driver", func() {
 	})
 })
 
+var _ = Describe("GCS Driver History", func() {
+	var (
+		servicer *StatefulFakeIOServicer
+		d        *GCSDriver
+	)
+
+	BeforeEach(func() {
+		servicer = &StatefulFakeIOServicer{
+			Buf:       gbytes.NewBuffer(),
+			versioned: true,
+		}
+
+		for _, v := range []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"} {
+			servicer.store(v)
+		}
+
+		d = &GCSDriver{
+			Servicer:   servicer,
+			BucketName: "test-bucket",
+			Key:        "test-key",
+		}
+	})
+
+	It("returns only the current version without a cursor", func() {
+		versions, err := d.Check(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+	})
+
+	It("returns every version from the cursor onwards, oldest first", func() {
+		cursor := semver.MustParse("1.1.0")
+		versions, err := d.Check(&cursor)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(Equal([]semver.Version{
+			semver.MustParse("1.1.0"),
+			semver.MustParse("1.2.0"),
+			semver.MustParse("2.0.0"),
+		}))
+	})
+
+	It("returns all generations when the cursor predates them", func() {
+		cursor := semver.MustParse("0.0.1")
+		versions, err := d.Check(&cursor)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(HaveLen(4))
+		Expect(versions[0]).To(Equal(semver.MustParse("1.0.0")))
+	})
+
+	It("returns an error when an old generation cannot be parsed", func() {
+		servicer.store("garbage")
+		servicer.store("3.0.0")
+
+		cursor := semver.MustParse("1.0.0")
+		_, err := d.Check(&cursor)
+		Expect(err).To(MatchError(ContainSubstring("parsing number in bucket:")))
+	})
+
+	It("returns only the current version when the bucket is not versioned", func() {
+		servicer.versioned = false
+		servicer.store("3.0.0")
+
+		cursor := semver.MustParse("1.0.0")
+		versions, err := d.Check(&cursor)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(Equal([]semver.Version{semver.MustParse("3.0.0")}))
+	})
+
+	It("returns only the current version when listing is forbidden", func() {
+		s := &FakeIOServicer{
+			Body:      "2.0.0",
+			ListError: &googleapi.Error{Code: http.StatusForbidden},
+		}
+		d.Servicer = s
+
+		cursor := semver.MustParse("1.0.0")
+		versions, err := d.Check(&cursor)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+	})
+})
+
 var _ = Describe("GCS Driver Lifecycle", func() {
 	It("handles wrapped ErrObjectNotExist from newer GCS library versions", func() {
 		wrappedErr := fmt.Errorf("%w: %w", storage.ErrObjectNotExist, fmt.Errorf("googleapi: Error 404: No such object"))
@@ -315,20 +412,23 @@ var _ = Describe("GCS Driver Lifecycle", func() {
 })
 
 type FakeIOServicer struct {
-	Body string
-	Buf  *gbytes.Buffer
+	Body       string
+	Generation int64
+	Buf        *gbytes.Buffer
 
-	BucketName string
-	ObjectName string
+	BucketName    string
+	ObjectName    string
+	PutGeneration *int64
 
-	GetError error
+	GetError  error
+	ListError error
 }
 
-func (s *FakeIOServicer) GetObject(bucketName, objectName string) (io.ReadCloser, error) {
+func (s *FakeIOServicer) GetObject(bucketName, objectName string) (io.ReadCloser, int64, error) {
 	s.BucketName = bucketName
 	s.ObjectName = objectName
 
-	return io.NopCloser(strings.NewReader(s.Body)), s.GetError
+	return io.NopCloser(strings.NewReader(s.Body)), s.Generation, s.GetError
 }
 
 func (s *FakeIOServicer) PutObject(bucketName, objectName string) (io.WriteCloser, error) {
@@ -338,27 +438,90 @@ func (s *FakeIOServicer) PutObject(bucketName, objectName string) (io.WriteClose
 	return s.Buf, nil
 }
 
+func (s *FakeIOServicer) GetObjectGeneration(bucketName, objectName string, generation int64) (io.ReadCloser, error) {
+	return nil, storage.ErrObjectNotExist
+}
+
+func (s *FakeIOServicer) ListGenerations(bucketName, objectName string) ([]int64, error) {
+	return nil, s.ListError
+}
+
+func (s *FakeIOServicer) PutObjectIfGeneration(bucketName, objectName string, generation int64) (io.WriteCloser, error) {
+	s.PutGeneration = &generation
+
+	return s.PutObject(bucketName, objectName)
+}
+
+// StatefulFakeIOServicer keeps the object in memory and honours generation
+// preconditions. Each entry in concurrentWrites is stored right before the
+// next conditional write lands, simulating another pipeline winning the race.
 type StatefulFakeIOServicer struct {
 	storedVersion string
 	objectExists  bool
+	generation    int64
 	Buf           *gbytes.Buffer
+
+	// versioned keeps noncurrent generations around, like a bucket with
+	// object versioning enabled
+	versioned bool
+	history   map[int64]string
+
+	concurrentWrites []string
+	conditionalPuts  int
 }
 
-func (s *StatefulFakeIOServicer) GetObject(bucketName, objectName string) (io.ReadCloser, error) {
+func (s *StatefulFakeIOServicer) store(v string) {
+	s.storedVersion = v
+	s.objectExists = true
+	s.generation++
+
+	if s.history == nil || !s.versioned {
+		s.history = map[int64]string{}
+	}
+	s.history[s.generation] = v
+}
+
+func (s *StatefulFakeIOServicer) GetObject(bucketName, objectName string) (io.ReadCloser, int64, error) {
 	if !s.objectExists {
-		return io.NopCloser(strings.NewReader("")), storage.ErrObjectNotExist
+		return io.NopCloser(strings.NewReader("")), 0, storage.ErrObjectNotExist
+	}
+	return io.NopCloser(strings.NewReader(s.storedVersion)), s.generation, nil
+}
+
+func (s *StatefulFakeIOServicer) GetObjectGeneration(bucketName, objectName string, generation int64) (io.ReadCloser, error) {
+	v, found := s.history[generation]
+	if !found {
+		return nil, storage.ErrObjectNotExist
 	}
-	return io.NopCloser(strings.NewReader(s.storedVersion)), nil
+	return io.NopCloser(strings.NewReader(v)), nil
+}
+
+func (s *StatefulFakeIOServicer) ListGenerations(bucketName, objectName string) ([]int64, error) {
+	var generations []int64
+	for generation := s.generation; generation > 0; generation-- {
+		if _, found := s.history[generation]; found {
+			generations = append(generations, generation)
+		}
+	}
+	return generations, nil
 }
 
 func (s *StatefulFakeIOServicer) PutObject(bucketName, objectName string) (io.WriteCloser, error) {
 	return &statefulWriter{servicer: s, buf: s.Buf}, nil
 }
 
+func (s *StatefulFakeIOServicer) PutObjectIfGeneration(bucketName, objectName string, generation int64) (io.WriteCloser, error) {
+	s.conditionalPuts++
+	return &statefulWriter{servicer: s, buf: s.Buf, conditional: true, generation: generation}, nil
+}
+
 type statefulWriter struct {
 	servicer *StatefulFakeIOServicer
 	buf      *gbytes.Buffer
 	data     []byte
+
+	conditional bool
+	generation  int64
 }
 
 func (w *statefulWriter) Write(p []byte) (n int, err error) {
@@ -367,7 +530,42 @@ func (w *statefulWriter) Write(p []byte) (n int, err error) {
 }
 
 func (w *statefulWriter) Close() error {
-	w.servicer.storedVersion = string(w.data)
-	w.servicer.objectExists = true
+	s := w.servicer
+
+	if w.conditional {
+		if len(s.concurrentWrites) > 0 {
+			s.store(s.concurrentWrites[0])
+			s.concurrentWrites = s.concurrentWrites[1:]
+		}
+
+		if (w.generation == 0 && s.objectExists) || (w.generation != 0 && w.generation != s.generation) {
+			return &googleapi.Error{Code: http.StatusPreconditionFailed}
+		}
+	}
+
+	s.store(string(w.data))
 	return w.buf.Close()
 }
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func gcsCASSubject() casSubject {
+	servicer := &StatefulFakeIOServicer{
+		Buf: gbytes.NewBuffer(),
+	}
+
+	return casSubject{
+		driver: &GCSDriver{
+			InitialVersion: semver.Version{Major: 1},
+			Servicer:       servicer,
+			BucketName:     "test-bucket",
+			Key:            "test-key",
+			RetryBackoff:   time.Millisecond,
+		},
+		store:   servicer.store,
+		current: func() string { return servicer.storedVersion },
+		race:    func(versions ...string) { servicer.concurrentWrites = versions },
+	}
+}
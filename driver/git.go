@@ -3,30 +3,36 @@ package driver
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/mail"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"path"
 	"slices"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/blang/semver"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"golang.org/x/crypto/ssh"
+
 	"github.com/concourse/semver-resource/version"
 )
 
-var gitRepoDir string
-var privateKeyPath string
-var netRcPath string
-
 var ErrEncryptedKey = errors.New("private keys with passphrases are not supported")
 var RetriesOnErrorWriteVersion = 3
 
-func init() {
-	gitRepoDir = filepath.Join(os.TempDir(), "semver-git-repo")
-	privateKeyPath = filepath.Join(os.TempDir(), "private-key")
-	netRcPath = filepath.Join(os.Getenv("HOME"), ".netrc")
-}
+// defaultGitUser is the commit identity used when neither git_user nor the
+// global git config provide one.
+var defaultGitUser = object.Signature{Name: "git", Email: "git@localhost"}
 
 type GitDriver struct {
 	InitialVersion semver.Version
@@ -43,12 +49,7 @@ type GitDriver struct {
 }
 
 func (driver *GitDriver) Bump(bump version.Bump) (semver.Version, error) {
-	err := driver.setUpAuth()
-	if err != nil {
-		return semver.Version{}, err
-	}
-
-	err = driver.setUserInfo()
+	auth, err := driver.auth()
 	if err != nil {
 		return semver.Version{}, err
 	}
@@ -56,12 +57,15 @@ func (driver *GitDriver) Bump(bump version.Bump) (semver.Version, error) {
 	var newVersion semver.Version
 
 	for range RetriesOnErrorWriteVersion {
-		err = driver.setUpRepo()
+		var repo *git.Repository
+		repo, err = driver.cloneRepo(auth, 1)
 		if err != nil {
 			return semver.Version{}, err
 		}
 
-		currentVersion, exists, err := driver.readVersion()
+		var currentVersion semver.Version
+		var exists bool
+		currentVersion, exists, err = driver.readVersion(repo)
 		if err != nil {
 			return semver.Version{}, err
 		}
@@ -70,11 +74,13 @@ func (driver *GitDriver) Bump(bump version.Bump) (semver.Version, error) {
 			currentVersion = driver.InitialVersion
 		}
 
-		newVersion = bump.Apply(currentVersion)
+		newVersion, err = version.Apply(bump, currentVersion)
+		if err != nil {
+			return semver.Version{}, err
+		}
 
-		var wrote bool
-		wrote, err = driver.writeVersion(newVersion)
-		if wrote {
+		err = driver.writeVersion(repo, auth, newVersion)
+		if err == nil || !isRejectedPush(err) {
 			break
 		}
 	}
@@ -86,25 +92,20 @@ func (driver *GitDriver) Bump(bump version.Bump) (semver.Version, error) {
 }
 
 func (driver *GitDriver) Set(newVersion semver.Version) error {
-	err := driver.setUpAuth()
-	if err != nil {
-		return err
-	}
-
-	err = driver.setUserInfo()
+	auth, err := driver.auth()
 	if err != nil {
 		return err
 	}
 
 	for range RetriesOnErrorWriteVersion {
-		err = driver.setUpRepo()
+		var repo *git.Repository
+		repo, err = driver.cloneRepo(auth, 1)
 		if err != nil {
 			return err
 		}
 
-		var wrote bool
-		wrote, err = driver.writeVersion(newVersion)
-		if wrote {
+		err = driver.writeVersion(repo, auth, newVersion)
+		if err == nil || !isRejectedPush(err) {
 			break
 		}
 	}
@@ -116,22 +117,38 @@ func (driver *GitDriver) Set(newVersion semver.Version) error {
 }
 
 func (driver *GitDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
-	err := driver.setUpAuth()
+	auth, err := driver.auth()
 	if err != nil {
 		return nil, err
 	}
 
-	err = driver.skipSSLVerificationIfNeeded()
+	repo, err := driver.cloneRepo(auth, 1)
 	if err != nil {
 		return nil, err
 	}
 
-	err = driver.setUpRepo()
+	currentVersion, exists, err := driver.readVersion(repo)
 	if err != nil {
 		return nil, err
 	}
 
-	currentVersion, exists, err := driver.readVersion()
+	if !exists {
+		return []semver.Version{driver.InitialVersion}, nil
+	}
+
+	// Concourse passes the last version it saw as the cursor, so the history
+	// is only needed for a "fly check-resource --from" an older version
+	if cursor == nil || cursor.GTE(currentVersion) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	repo, err = driver.cloneRepo(auth, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// the branch may have moved on since the first clone
+	currentVersion, exists, err = driver.readVersion(repo)
 	if err != nil {
 		return nil, err
 	}
@@ -140,247 +157,197 @@ func (driver *GitDriver) Check(cursor *semver.Version) ([]semver.Version, error)
 		return []semver.Version{driver.InitialVersion}, nil
 	}
 
-	// Handle a "fly check-resource --from <cursor>" to bring back old versions
-	if cursor != nil {
-		return driver.getOldVersions(cursor, currentVersion)
-	}
+	return driver.getOldVersions(repo, cursor, currentVersion)
+}
 
-	return []semver.Version{currentVersion}, nil
+// cloneRepo clones the branch into memory, so nothing is shared between
+// invocations. A depth of 1 only fetches the head commit, 0 the full history.
+func (driver *GitDriver) cloneRepo(auth transport.AuthMethod, depth int) (*git.Repository, error) {
+	return git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
+		URL:             driver.URI,
+		Auth:            auth,
+		ReferenceName:   plumbing.NewBranchReferenceName(driver.Branch),
+		SingleBranch:    true,
+		Depth:           depth,
+		InsecureSkipTLS: driver.SkipSSLVerification,
+		Progress:        os.Stderr,
+	})
 }
 
-func (driver *GitDriver) setUpRepo() error {
-	_, err := os.Stat(gitRepoDir)
-	if err != nil {
-		// Use sparse checkout to only fetch the version file
-		gitClone := exec.Command("git", "clone", "--no-checkout", "--filter=blob:none", driver.URI, gitRepoDir)
-		gitClone.Stdout = os.Stderr
-		gitClone.Stderr = os.Stderr
-		if err := gitClone.Run(); err != nil {
-			return err
-		}
+func (driver *GitDriver) auth() (transport.AuthMethod, error) {
+	return gitAuth(driver.URI, driver.PrivateKey, driver.Username, driver.Password)
+}
 
-		// Initialize sparse checkout
-		gitSparseInit := exec.Command("git", "sparse-checkout", "init", "--cone")
-		gitSparseInit.Dir = gitRepoDir
-		gitSparseInit.Stdout = os.Stderr
-		gitSparseInit.Stderr = os.Stderr
-		if err := gitSparseInit.Run(); err != nil {
-			return err
-		}
+// gitAuth returns the credentials to use for the repository's transport: the
+// private key for SSH remotes, or username and password for HTTP(S) remotes.
+func gitAuth(uri, privateKey, username, password string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(uri)
+	if err != nil {
+		return nil, err
+	}
 
-		// Set sparse checkout to include only the version file
-		gitSparseSet := exec.Command("git", "sparse-checkout", "set", filepath.Dir(driver.File))
-		gitSparseSet.Dir = gitRepoDir
-		gitSparseSet.Stdout = os.Stderr
-		gitSparseSet.Stderr = os.Stderr
-		if err := gitSparseSet.Run(); err != nil {
-			// If directory is root, set the file directly
-			gitSparseSet = exec.Command("git", "sparse-checkout", "set", driver.File)
-			gitSparseSet.Dir = gitRepoDir
-			gitSparseSet.Stdout = os.Stderr
-			gitSparseSet.Stderr = os.Stderr
-			if err := gitSparseSet.Run(); err != nil {
-				return err
+	var signer ssh.Signer
+	if len(privateKey) > 0 {
+		trimmedKey := strings.TrimSuffix(privateKey, "\n")
+		signer, err = ssh.ParsePrivateKey([]byte(trimmedKey + "\n"))
+		if err != nil {
+			var passphraseMissing *ssh.PassphraseMissingError
+			if errors.As(err, &passphraseMissing) {
+				return nil, ErrEncryptedKey
 			}
+
+			return nil, err
 		}
+	}
 
-		// Checkout the branch
-		gitCheckout := exec.Command("git", "checkout", driver.Branch)
-		gitCheckout.Dir = gitRepoDir
-		gitCheckout.Stdout = os.Stderr
-		gitCheckout.Stderr = os.Stderr
-		if err := gitCheckout.Run(); err != nil {
-			return err
+	switch endpoint.Protocol {
+	case "ssh":
+		if signer == nil {
+			return nil, nil
 		}
-	} else {
-		gitFetch := exec.Command("git", "fetch", "origin", driver.Branch)
-		gitFetch.Dir = gitRepoDir
-		gitFetch.Stdout = os.Stderr
-		gitFetch.Stderr = os.Stderr
-		if err := gitFetch.Run(); err != nil {
-			return err
+
+		user := endpoint.User
+		if user == "" {
+			user = "git"
 		}
-	}
 
-	gitCheckout := exec.Command("git", "reset", "--hard", "origin/"+driver.Branch)
-	gitCheckout.Dir = gitRepoDir
-	gitCheckout.Stdout = os.Stderr
-	gitCheckout.Stderr = os.Stderr
-	if err := gitCheckout.Run(); err != nil {
-		return err
-	}
+		keys := &gitssh.PublicKeys{User: user, Signer: signer}
+		keys.HostKeyCallback = ssh.InsecureIgnoreHostKey()
 
-	return nil
-}
+		return keys, nil
 
-func (driver *GitDriver) skipSSLVerificationIfNeeded() error {
-	if driver.SkipSSLVerification {
-		gitSkipSSLVerification := exec.Command("git", "config", "--global", "http.sslVerify", "false")
-		gitSkipSSLVerification.Stdout = os.Stderr
-		gitSkipSSLVerification.Stderr = os.Stderr
-		if err := gitSkipSSLVerification.Run(); err != nil {
-			return err
+	case "http", "https":
+		if len(username) == 0 || len(password) == 0 {
+			return nil, nil
 		}
-	}
 
-	return nil
+		return &githttp.BasicAuth{
+			Username: username,
+			Password: password,
+		}, nil
+
+	default:
+		return nil, nil
+	}
 }
 
-func (driver *GitDriver) setUpAuth() error {
-	_, err := os.Stat(netRcPath)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return err
+// gitSignature returns the commit identity: git_user if given, falling back
+// to the global git config and then to defaultGitUser.
+func gitSignature(repo *git.Repository, gitUser string) (*object.Signature, error) {
+	signature := defaultGitUser
+
+	cfg, err := repo.ConfigScoped(config.GlobalScope)
+	if err == nil {
+		if cfg.User.Name != "" {
+			signature.Name = cfg.User.Name
 		}
-	} else {
-		err := os.Remove(netRcPath)
-		if err != nil {
-			return err
+		if cfg.User.Email != "" {
+			signature.Email = cfg.User.Email
 		}
 	}
 
-	if len(driver.PrivateKey) > 0 {
-		err := driver.setUpKey()
+	if len(gitUser) > 0 {
+		e, err := mail.ParseAddress(gitUser)
 		if err != nil {
-			return err
+			return nil, err
 		}
-	}
 
-	if len(driver.Username) > 0 && len(driver.Password) > 0 {
-		err := driver.setUpUsernamePassword()
-		if err != nil {
-			return err
+		if len(e.Name) > 0 {
+			signature.Name = e.Name
 		}
-	}
 
-	return nil
-}
-
-func (driver *GitDriver) setUpKey() error {
-	_, err := os.Stat(privateKeyPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			privateKey := strings.TrimSuffix(driver.PrivateKey, "\n")
-			err := os.WriteFile(privateKeyPath, []byte(privateKey+"\n"), 0600)
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
+		signature.Email = e.Address
 	}
 
-	if isPrivateKeyEncrypted(privateKeyPath) {
-		return ErrEncryptedKey
-	}
+	signature.When = time.Now()
 
-	return os.Setenv("GIT_SSH_COMMAND", "ssh -o StrictHostKeyChecking=no -i "+privateKeyPath)
+	return &signature, nil
 }
 
-func isPrivateKeyEncrypted(path string) bool {
-	passphrase := ``
-	cmd := exec.Command(`ssh-keygen`, `-y`, `-f`, path, `-P`, passphrase)
-	err := cmd.Run()
-
-	return err != nil
+// filePath returns the version file's path relative to the repository root.
+func (driver *GitDriver) filePath() string {
+	return strings.TrimPrefix(path.Clean("/"+driver.File), "/")
 }
 
-func (driver *GitDriver) setUpUsernamePassword() error {
-	_, err := os.Stat(netRcPath)
+func (driver *GitDriver) readVersion(repo *git.Repository) (semver.Version, bool, error) {
+	head, err := repo.Head()
 	if err != nil {
-		if os.IsNotExist(err) {
-			content := fmt.Sprintf("default login %s password %s", driver.Username, driver.Password)
-			err := os.WriteFile(netRcPath, []byte(content), 0600)
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
+		return semver.Version{}, false, err
 	}
 
-	return nil
-}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return semver.Version{}, false, err
+	}
 
-func (driver *GitDriver) setUserInfo() error {
-	if len(driver.GitUser) == 0 {
-		return nil
+	currentVersion, err := driver.versionAt(commit)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return semver.Version{}, false, nil
 	}
 
-	e, err := mail.ParseAddress(driver.GitUser)
 	if err != nil {
-		return err
+		return semver.Version{}, false, err
 	}
 
-	if len(e.Name) > 0 {
-		gitName := exec.Command("git", "config", "--global", "user.name", e.Name)
-		gitName.Stdout = os.Stderr
-		gitName.Stderr = os.Stderr
-		if err := gitName.Run(); err != nil {
-			return err
-		}
-	}
+	return currentVersion, true, nil
+}
 
-	gitEmail := exec.Command("git", "config", "--global", "user.email", e.Address)
-	gitEmail.Stdout = os.Stderr
-	gitEmail.Stderr = os.Stderr
-	if err := gitEmail.Run(); err != nil {
-		return err
+// versionAt returns the version in the file as of the given commit.
+func (driver *GitDriver) versionAt(commit *object.Commit) (semver.Version, error) {
+	file, err := commit.File(driver.filePath())
+	if err != nil {
+		return semver.Version{}, err
 	}
-	return nil
-}
 
-func (driver *GitDriver) readVersion() (semver.Version, bool, error) {
-	var currentVersionStr string
-	versionFile, err := os.Open(filepath.Join(gitRepoDir, driver.File))
+	contents, err := file.Contents()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return semver.Version{}, false, nil
-		}
+		return semver.Version{}, err
+	}
 
-		return semver.Version{}, false, err
+	var versionStr string
+	_, err = fmt.Sscanf(contents, "%s", &versionStr)
+	if err != nil {
+		return semver.Version{}, err
 	}
 
-	defer versionFile.Close()
+	return semver.Parse(strings.TrimSpace(versionStr))
+}
 
-	_, err = fmt.Fscanf(versionFile, "%s", &currentVersionStr)
+func (driver *GitDriver) writeVersion(repo *git.Repository, auth transport.AuthMethod, newVersion semver.Version) error {
+	head, err := repo.Head()
 	if err != nil {
-		return semver.Version{}, false, err
+		return err
 	}
 
-	currentVersion, err := semver.Parse(strings.TrimSpace(currentVersionStr))
+	worktree, err := repo.Worktree()
 	if err != nil {
-		return semver.Version{}, false, err
+		return err
 	}
 
-	return currentVersion, true, nil
-}
+	filePath := driver.filePath()
+	contents := []byte(newVersion.String() + "\n")
 
-const nothingToCommitString = "nothing to commit"
-const falsePushString = "Everything up-to-date"
-
-func (driver *GitDriver) writeVersion(newVersion semver.Version) (bool, error) {
+	existing, err := util.ReadFile(worktree.Filesystem, filePath)
+	if err == nil && string(existing) == string(contents) {
+		fmt.Fprintln(os.Stderr, "Nothing to commit, skipping version push")
+		return nil
+	}
 
-	path := filepath.Dir(driver.File)
-	if path != "/" && path != "." {
-		err := os.MkdirAll(filepath.Join(gitRepoDir, path), 0755)
-		if err != nil {
-			return false, err
-		}
+	err = worktree.Filesystem.MkdirAll(path.Dir(filePath), 0755)
+	if err != nil {
+		return err
 	}
 
-	err := os.WriteFile(filepath.Join(gitRepoDir, driver.File), []byte(newVersion.String()+"\n"), 0644)
+	err = util.WriteFile(worktree.Filesystem, filePath, contents, 0644)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	gitAdd := exec.Command("git", "add", driver.File)
-	gitAdd.Dir = gitRepoDir
-	gitAdd.Stdout = os.Stderr
-	gitAdd.Stderr = os.Stderr
-	if err := gitAdd.Run(); err != nil {
-		return false, err
+	_, err = worktree.Add(filePath)
+	if err != nil {
+		return err
 	}
+
 	var commitMessage string
 	if driver.CommitMessage == "" {
 		commitMessage = "bump to " + newVersion.String()
@@ -389,74 +356,125 @@ func (driver *GitDriver) writeVersion(newVersion semver.Version) (bool, error) {
 		commitMessage = strings.ReplaceAll(commitMessage, "%file%", driver.File)
 	}
 
-	gitCommit := exec.Command("git", "commit", "-m", commitMessage)
-	gitCommit.Dir = gitRepoDir
+	signature, err := gitSignature(repo, driver.GitUser)
+	if err != nil {
+		return err
+	}
 
-	commitOutput, err := gitCommit.CombinedOutput()
+	_, err = worktree.Commit(commitMessage, &git.CommitOptions{
+		Author: signature,
+	})
+	if err != nil {
+		return err
+	}
 
-	if strings.Contains(string(commitOutput), nothingToCommitString) {
-		os.Stderr.Write([]byte("Nothing to commit, skipping version push\n"))
-		return true, nil
+	// Require the branch to still be at the cloned commit: a shallow clone
+	// cannot tell a fast-forward from the remote having moved on.
+	branch := plumbing.NewBranchReferenceName(driver.Branch)
+	err = repo.Push(&git.PushOptions{
+		Auth:              auth,
+		RefSpecs:          []config.RefSpec{config.RefSpec(branch + ":" + branch)},
+		RequireRemoteRefs: []config.RefSpec{config.RefSpec(head.Hash().String() + ":" + branch.String())},
+		InsecureSkipTLS:   driver.SkipSSLVerification,
+		Progress:          os.Stderr,
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		fmt.Fprintln(os.Stderr, "Everything up-to-date")
+		return nil
 	}
 
-	if err != nil {
-		os.Stderr.Write(commitOutput)
-		return false, err
+	if err != nil && driver.branchMoved(repo, auth, head.Hash()) {
+		return fmt.Errorf("%w: %w", errBranchMoved, err)
 	}
 
-	gitPush := exec.Command("git", "push", "origin", "HEAD:"+driver.Branch)
-	gitPush.Dir = gitRepoDir
+	return err
+}
 
-	pushOutput, err := gitPush.CombinedOutput()
+// errBranchMoved is returned when a push failed because the branch moved on
+// the remote since it was cloned.
+var errBranchMoved = errors.New("branch moved on the remote")
 
-	if strings.Contains(string(pushOutput), falsePushString) {
-		os.Stderr.Write(pushOutput)
-		return true, nil
+// branchMoved reports whether the branch on the remote is no longer at the
+// given commit. go-git does not tell its rejections of a push apart from
+// other failures, so the remote is asked again after a failed push.
+func (driver *GitDriver) branchMoved(repo *git.Repository, auth transport.AuthMethod, cloned plumbing.Hash) bool {
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return false
 	}
 
+	refs, err := remote.List(&git.ListOptions{
+		Auth:            auth,
+		InsecureSkipTLS: driver.SkipSSLVerification,
+	})
 	if err != nil {
-		os.Stderr.Write(pushOutput)
-		return false, err
+		return false
+	}
+
+	branch := plumbing.NewBranchReferenceName(driver.Branch)
+	for _, ref := range refs {
+		if ref.Name() == branch {
+			return ref.Hash() != cloned
+		}
+	}
+
+	return true
+}
+
+// isRejectedPush reports whether a push failed because the branch moved on the
+// remote since it was cloned, in which case the version is re-read and the
+// push retried. Remotes only report their rejections in the error message.
+func isRejectedPush(err error) bool {
+	if errors.Is(err, errBranchMoved) || errors.Is(err, git.ErrForceNeeded) {
+		return true
 	}
 
-	return true, nil
+	msg := err.Error()
+	return strings.Contains(msg, "non-fast-forward") || strings.Contains(msg, "fetch first")
 }
 
 // getOldVersions() goes back in git history to find all versions newer than the cursor
 // The loop ends when we find a version older than the cursor or reach the beginning of history
-func (driver *GitDriver) getOldVersions(cursor *semver.Version, currentVersion semver.Version) ([]semver.Version, error) {
+func (driver *GitDriver) getOldVersions(repo *git.Repository, cursor *semver.Version, currentVersion semver.Version) ([]semver.Version, error) {
 	// Supplied cursor version is newer or equal to current, so we do not need to go back in history
 	if cursor.GTE(currentVersion) {
 		return []semver.Version{currentVersion}, nil
 	}
 
+	filePath := driver.filePath()
+	commits, err := repo.Log(&git.LogOptions{FileName: &filePath})
+	if err != nil {
+		return nil, err
+	}
+	defer commits.Close()
+
+	// The first commit touching the file holds the current version
+	_, err = commits.Next()
+	if err != nil {
+		return nil, err
+	}
+
 	oldVersions := []semver.Version{currentVersion}
-	counter := 1
 	for {
-		// Use git log to get the previous commit hash
-		gitLogPreviousCommit := exec.Command("git", "log", "--pretty=format:%H", "-n", "1", "--skip", strconv.Itoa(counter), driver.File)
-		gitLogPreviousCommit.Dir = gitRepoDir
-		commitHashBytes, err := gitLogPreviousCommit.Output()
-		if err != nil {
-			return nil, err
-		}
-		commitHash := strings.TrimSpace(string(commitHashBytes))
+		commit, err := commits.Next()
 
-		// We have reached the beginning of history with a non-existing commit, so early return what we have
-		if len(commitHash) == 0 {
+		// We have reached the beginning of history, so early return what we have
+		if errors.Is(err, io.EOF) {
 			slices.Reverse(oldVersions)
 			return oldVersions, nil
 		}
 
-		// Use git show to view the file content at that commit
-		gitShowPreviousVersion := exec.Command("git", "show", commitHash+":"+driver.File)
-		gitShowPreviousVersion.Dir = gitRepoDir
-		previousVersionBytes, err := gitShowPreviousVersion.Output()
 		if err != nil {
 			return nil, err
 		}
-		previousVersionStr := strings.TrimSpace(string(previousVersionBytes))
-		previousVersion, err := semver.Parse(previousVersionStr)
+
+		previousVersion, err := driver.versionAt(commit)
+		if errors.Is(err, object.ErrFileNotFound) {
+			// the file was deleted in this commit, so nothing older is relevant
+			slices.Reverse(oldVersions)
+			return oldVersions, nil
+		}
+
 		if err != nil {
 			return nil, err
 		}
@@ -469,6 +487,5 @@ func (driver *GitDriver) getOldVersions(cursor *semver.Version, currentVersion s
 
 		// Cursor is older or equal to previous version, so include previous version and continue
 		oldVersions = append(oldVersions, previousVersion)
-		counter++
 	}
 }
@@ -0,0 +1,272 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+
+	"github.com/concourse/semver-resource/version"
+)
+
+// GitTagsDriver stores versions as annotated tags in a repository rather than
+// in a file. The current version is the highest tag that parses as semver
+// once TagPrefix is removed.
+type GitTagsDriver struct {
+	InitialVersion semver.Version
+
+	URI                 string
+	Branch              string
+	PrivateKey          string
+	Username            string
+	Password            string
+	TagPrefix           string
+	GitUser             string
+	CommitMessage       string
+	SkipSSLVerification bool
+}
+
+func (driver *GitTagsDriver) Bump(bump version.Bump) (semver.Version, error) {
+	auth, err := driver.auth()
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	var newVersion semver.Version
+
+	for range RetriesOnErrorWriteVersion {
+		var versions []semver.Version
+		versions, err = driver.listVersions(auth)
+		if err != nil {
+			return semver.Version{}, err
+		}
+
+		currentVersion := driver.InitialVersion
+		if len(versions) > 0 {
+			currentVersion = versions[len(versions)-1]
+		}
+
+		newVersion, err = version.Apply(bump, currentVersion)
+		if err != nil {
+			return semver.Version{}, err
+		}
+
+		// the current version is already tagged, unless it is the initial
+		// version of a repository without version tags
+		if len(versions) > 0 && newVersion.String() == currentVersion.String() {
+			fmt.Fprintf(os.Stderr, "Tag %s already exists, skipping version push\n", driver.tagName(newVersion))
+			return newVersion, nil
+		}
+
+		// a concurrent bump pushing the same tag makes ours fail, in which
+		// case the tags are listed again and the bump re-applied
+		err = driver.pushTag(auth, newVersion)
+		if err == nil || !(errors.Is(err, errGitTagExists) || isRejectedPush(err)) {
+			break
+		}
+	}
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	return newVersion, nil
+}
+
+func (driver *GitTagsDriver) Set(newVersion semver.Version) error {
+	auth, err := driver.auth()
+	if err != nil {
+		return err
+	}
+
+	versions, err := driver.listVersions(auth)
+	if err != nil {
+		return err
+	}
+
+	if slices.ContainsFunc(versions, newVersion.Equals) {
+		fmt.Fprintf(os.Stderr, "Tag %s already exists, skipping version push\n", driver.tagName(newVersion))
+		return nil
+	}
+
+	return driver.pushTag(auth, newVersion)
+}
+
+func (driver *GitTagsDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	auth, err := driver.auth()
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := driver.listVersions(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		return []semver.Version{driver.InitialVersion}, nil
+	}
+
+	currentVersion := versions[len(versions)-1]
+
+	// Supplied cursor version is newer or equal to current, so only the
+	// current version is relevant
+	if cursor == nil || cursor.GTE(currentVersion) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	// Handle a "fly check-resource --from <cursor>" by returning every tag
+	// from the cursor onwards
+	i, _ := slices.BinarySearchFunc(versions, *cursor, semver.Version.Compare)
+	return versions[i:], nil
+}
+
+func (driver *GitTagsDriver) auth() (transport.AuthMethod, error) {
+	return gitAuth(driver.URI, driver.PrivateKey, driver.Username, driver.Password)
+}
+
+func (driver *GitTagsDriver) tagName(v semver.Version) string {
+	return driver.TagPrefix + v.String()
+}
+
+// listRefs returns the references on the remote.
+func (driver *GitTagsDriver) listRefs(auth transport.AuthMethod) ([]*plumbing.Reference, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{driver.URI},
+	})
+
+	refs, err := remote.List(&git.ListOptions{
+		Auth:            auth,
+		InsecureSkipTLS: driver.SkipSSLVerification,
+	})
+	if err != nil {
+		// an empty repository has no tags yet
+		if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// listVersions returns the versions of all tags on the remote that start with
+// the prefix and parse as semver, in ascending order.
+func (driver *GitTagsDriver) listVersions(auth transport.AuthMethod) ([]semver.Version, error) {
+	refs, err := driver.listRefs(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []semver.Version
+	for _, ref := range refs {
+		if !ref.Name().IsTag() {
+			continue
+		}
+
+		tag, found := strings.CutPrefix(ref.Name().Short(), driver.TagPrefix)
+		if !found {
+			continue
+		}
+
+		v, err := semver.Parse(tag)
+		if err != nil {
+			continue
+		}
+
+		versions = append(versions, v)
+	}
+
+	semver.Sort(versions)
+	versions = slices.CompactFunc(versions, semver.Version.Equals)
+
+	return versions, nil
+}
+
+// errGitTagExists is returned when a push failed because another writer
+// pushed the same tag first.
+var errGitTagExists = errors.New("tag already exists")
+
+// pushTag creates an annotated tag for the version at the tip of the branch
+// and pushes it. Only the tip is cloned, as that is all the tag needs.
+func (driver *GitTagsDriver) pushTag(auth transport.AuthMethod, newVersion semver.Version) error {
+	repo, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+		URL:             driver.URI,
+		Auth:            auth,
+		ReferenceName:   plumbing.NewBranchReferenceName(driver.Branch),
+		SingleBranch:    true,
+		Depth:           1,
+		NoCheckout:      true,
+		Tags:            git.NoTags,
+		InsecureSkipTLS: driver.SkipSSLVerification,
+		Progress:        os.Stderr,
+	})
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+
+	signature, err := gitSignature(repo, driver.GitUser)
+	if err != nil {
+		return err
+	}
+
+	tagName := driver.tagName(newVersion)
+
+	message := "bump to " + newVersion.String()
+	if driver.CommitMessage != "" {
+		message = strings.ReplaceAll(driver.CommitMessage, "%version%", newVersion.String())
+	}
+
+	_, err = repo.CreateTag(tagName, head.Hash(), &git.CreateTagOptions{
+		Tagger:  signature,
+		Message: message,
+	})
+	if err != nil {
+		return err
+	}
+
+	tag := plumbing.NewTagReferenceName(tagName)
+	err = repo.Push(&git.PushOptions{
+		Auth:            auth,
+		RefSpecs:        []config.RefSpec{config.RefSpec(tag + ":" + tag)},
+		InsecureSkipTLS: driver.SkipSSLVerification,
+		Progress:        os.Stderr,
+	})
+	if err != nil {
+		// go-git does not tell a rejected push apart from other failures, so
+		// the remote is asked whether the tag appeared in the meantime
+		if driver.tagExists(auth, tag) {
+			return fmt.Errorf("pushing tag %s: %w", tagName, errGitTagExists)
+		}
+
+		return fmt.Errorf("pushing tag %s: %w", tagName, err)
+	}
+
+	return nil
+}
+
+// tagExists reports whether the tag exists on the remote.
+func (driver *GitTagsDriver) tagExists(auth transport.AuthMethod, tag plumbing.ReferenceName) bool {
+	refs, err := driver.listRefs(auth)
+	if err != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(refs, func(ref *plumbing.Reference) bool {
+		return ref.Name() == tag
+	})
+}
@@ -0,0 +1,204 @@
+package driver_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Git Tags Driver", func() {
+	var (
+		remote    string
+		d         *driver.GitTagsDriver
+		transport *hookedTransport
+	)
+
+	BeforeEach(func() {
+		// serve file:// remotes with git-upload-pack, as go-git's in-process
+		// server does not support shallow clones
+		DeferCleanup(client.InstallProtocol, "file", client.Protocols["file"])
+		transport = &hookedTransport{Transport: client.Protocols["file"]}
+		client.InstallProtocol("file", transport)
+
+		remote = newBareRepo()
+		commitToBranch(remote, "main", "README", "hello")
+
+		d = &driver.GitTagsDriver{
+			InitialVersion: semver.Version{Major: 1},
+			URI:            remote,
+			Branch:         "main",
+			TagPrefix:      "v",
+		}
+	})
+
+	Describe("Check", func() {
+		It("returns the initial version when there are no tags", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the highest tag matching the prefix", func() {
+			tagRemote(remote, "main", "v1.2.0", true)
+			tagRemote(remote, "main", "v1.10.0", false)
+			tagRemote(remote, "main", "v1.9.0", true)
+			tagRemote(remote, "main", "2.0.0", true)
+			tagRemote(remote, "main", "vNext", true)
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.10.0")}))
+		})
+
+		It("returns every tag from the cursor onwards, oldest first", func() {
+			for _, tag := range []string{"v1.0.0", "v1.1.0", "v1.1.1", "v2.0.0-rc.1", "v2.0.0"} {
+				tagRemote(remote, "main", tag, true)
+			}
+
+			cursor := semver.MustParse("1.1.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.1.0"),
+				semver.MustParse("1.1.1"),
+				semver.MustParse("2.0.0-rc.1"),
+				semver.MustParse("2.0.0"),
+			}))
+		})
+
+		It("returns the current version when the cursor is not older", func() {
+			tagRemote(remote, "main", "v1.0.0", true)
+
+			cursor := semver.MustParse("3.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("bumps the initial version when there are no tags", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(remoteTag(remote, "v1.1.0").Target).To(Equal(headOfBranch(remote, "main").Hash))
+		})
+
+		It("pushes an annotated tag for the bumped version at the tip of the branch", func() {
+			tagRemote(remote, "main", "v1.2.3", true)
+			commitToBranch(remote, "main", "README", "changed")
+
+			d.GitUser = "Some Body <somebody@example.com>"
+			d.CommitMessage = "release %version%"
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+
+			tag := remoteTag(remote, "v1.2.4")
+			Expect(tag.Target).To(Equal(headOfBranch(remote, "main").Hash))
+			Expect(tag.Message).To(HavePrefix("release 1.2.4"))
+			Expect(tag.Tagger.Email).To(Equal("somebody@example.com"))
+		})
+
+		It("keeps the version when the bump leaves it as it is", func() {
+			tagRemote(remote, "main", "v1.2.3", true)
+			before := remoteTag(remote, "v1.2.3").Hash
+
+			newVersion, err := d.Bump(version.FinalBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.3"))
+			Expect(remoteTag(remote, "v1.2.3").Hash).To(Equal(before))
+		})
+
+		It("re-applies the bump when another writer pushed the tag first", func() {
+			tagRemote(remote, "main", "v1.2.3", true)
+
+			raced := false
+			transport.beforeReceivePack = func() error {
+				if !raced {
+					raced = true
+					tagRemote(remote, "main", "v1.2.4", true)
+				}
+				return nil
+			}
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.5"))
+			Expect(remoteTag(remote, "v1.2.5").Target).To(Equal(headOfBranch(remote, "main").Hash))
+		})
+
+		It("does not retry pushes failing for other reasons", func() {
+			pushes := 0
+			transport.beforeReceivePack = func() error {
+				pushes++
+				return errors.New("permission denied")
+			}
+
+			_, err := d.Bump(version.PatchBump{})
+			Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			Expect(pushes).To(Equal(1))
+		})
+	})
+
+	Describe("Set", func() {
+		It("pushes a tag for the version", func() {
+			err := d.Set(semver.MustParse("4.5.6"))
+			Expect(err).NotTo(HaveOccurred())
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("4.5.6")}))
+		})
+
+		It("leaves an existing tag alone", func() {
+			tagRemote(remote, "main", "v4.5.6", true)
+			before := remoteTag(remote, "v4.5.6").Hash
+
+			err := d.Set(semver.MustParse("4.5.6"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remoteTag(remote, "v4.5.6").Hash).To(Equal(before))
+		})
+	})
+})
+
+// tagRemote tags the tip of the branch directly in the remote repository.
+func tagRemote(remote, branch, name string, annotated bool) {
+	repo, err := git.PlainOpen(remote)
+	Expect(err).NotTo(HaveOccurred())
+
+	var opts *git.CreateTagOptions
+	if annotated {
+		opts = &git.CreateTagOptions{
+			Tagger:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+			Message: name,
+		}
+	}
+
+	_, err = repo.CreateTag(name, headOfBranch(remote, branch).Hash, opts)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func remoteTag(remote, name string) *object.Tag {
+	repo, err := git.PlainOpen(remote)
+	Expect(err).NotTo(HaveOccurred())
+
+	ref, err := repo.Reference(plumbing.NewTagReferenceName(name), false)
+	Expect(err).NotTo(HaveOccurred())
+
+	tag, err := repo.TagObject(ref.Hash())
+	Expect(err).NotTo(HaveOccurred())
+
+	return tag
+}
@@ -0,0 +1,335 @@
+package driver_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	gittransport "github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Git Driver", func() {
+	var (
+		remote    string
+		d         *driver.GitDriver
+		transport *hookedTransport
+	)
+
+	BeforeEach(func() {
+		// serve file:// remotes with git-upload-pack, as go-git's in-process
+		// server does not support shallow clones
+		DeferCleanup(client.InstallProtocol, "file", client.Protocols["file"])
+		transport = &hookedTransport{Transport: client.Protocols["file"]}
+		client.InstallProtocol("file", transport)
+
+		remote = newBareRepo()
+
+		d = &driver.GitDriver{
+			InitialVersion: semver.Version{Major: 1},
+			URI:            remote,
+			Branch:         "version",
+			File:           "some/dir/version",
+		}
+	})
+
+	Describe("Check", func() {
+		It("returns the initial version when the file does not exist", func() {
+			commitToBranch(remote, "version", "other-file", "hello")
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the version in the file", func() {
+			commitToBranch(remote, "version", "some/dir/version", "2.3.4\n")
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("only reads the configured branch", func() {
+			commitToBranch(remote, "version", "some/dir/version", "2.3.4\n")
+			commitToBranch(remote, "other", "some/dir/version", "9.9.9\n")
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("returns every version from the cursor onwards, oldest first", func() {
+			for _, v := range []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"} {
+				commitToBranch(remote, "version", "some/dir/version", v+"\n")
+				commitToBranch(remote, "version", "unrelated", v)
+			}
+
+			cursor := semver.MustParse("1.1.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.1.0"),
+				semver.MustParse("1.2.0"),
+				semver.MustParse("2.0.0"),
+			}))
+		})
+
+		It("returns the current version when the cursor is not older", func() {
+			commitToBranch(remote, "version", "some/dir/version", "1.0.0\n")
+			commitToBranch(remote, "version", "some/dir/version", "2.0.0\n")
+
+			cursor := semver.MustParse("3.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+		})
+
+		It("only clones the history when the cursor is older", func() {
+			commitToBranch(remote, "version", "some/dir/version", "1.0.0\n")
+			commitToBranch(remote, "version", "some/dir/version", "2.0.0\n")
+
+			transport.fetches = 0
+			cursor := semver.MustParse("2.0.0")
+			_, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transport.fetches).To(Equal(1))
+
+			transport.fetches = 0
+			cursor = semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0"), semver.MustParse("2.0.0")}))
+			Expect(transport.fetches).To(Equal(2))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("bumps the initial version when the file does not exist", func() {
+			commitToBranch(remote, "version", "other-file", "hello")
+
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(fileOnBranch(remote, "version", "some/dir/version")).To(Equal("1.1.0\n"))
+		})
+
+		It("commits and pushes the bumped version", func() {
+			commitToBranch(remote, "version", "some/dir/version", "1.2.3\n")
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(fileOnBranch(remote, "version", "some/dir/version")).To(Equal("1.2.4\n"))
+
+			commit := headOfBranch(remote, "version")
+			Expect(commit.Message).To(Equal("bump to 1.2.4"))
+			Expect(commit.Author.Email).NotTo(BeEmpty())
+		})
+
+		It("re-clones and re-applies the bump when the push is rejected", func() {
+			commitToBranch(remote, "version", "some/dir/version", "1.2.3\n")
+
+			raced := false
+			transport.beforeReceivePack = func() error {
+				if !raced {
+					raced = true
+					commitToBranch(remote, "version", "some/dir/version", "1.5.0\n")
+				}
+				return nil
+			}
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.5.1"))
+			Expect(fileOnBranch(remote, "version", "some/dir/version")).To(Equal("1.5.1\n"))
+
+			parent, err := headOfBranch(remote, "version").Parent(0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parent.Message).To(Equal("test commit"))
+		})
+
+		It("does not retry pushes failing for other reasons", func() {
+			commitToBranch(remote, "version", "some/dir/version", "1.2.3\n")
+
+			pushes := 0
+			transport.beforeReceivePack = func() error {
+				pushes++
+				return errors.New("permission denied")
+			}
+
+			_, err := d.Bump(version.PatchBump{})
+			Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			Expect(pushes).To(Equal(1))
+			Expect(fileOnBranch(remote, "version", "some/dir/version")).To(Equal("1.2.3\n"))
+		})
+	})
+
+	Describe("Set", func() {
+		BeforeEach(func() {
+			commitToBranch(remote, "version", "some/dir/version", "1.2.3\n")
+		})
+
+		It("commits the version as the configured git user with the configured message", func() {
+			d.GitUser = "Some Body <somebody@example.com>"
+			d.CommitMessage = "set %file% to %version% [skip ci]"
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileOnBranch(remote, "version", "some/dir/version")).To(Equal("5.0.0\n"))
+
+			commit := headOfBranch(remote, "version")
+			Expect(commit.Message).To(Equal("set some/dir/version to 5.0.0 [skip ci]"))
+			Expect(commit.Author.Name).To(Equal("Some Body"))
+			Expect(commit.Author.Email).To(Equal("somebody@example.com"))
+		})
+
+		It("does not commit when the version is unchanged", func() {
+			before := headOfBranch(remote, "version").Hash
+
+			err := d.Set(semver.MustParse("1.2.3"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(headOfBranch(remote, "version").Hash).To(Equal(before))
+		})
+
+		It("rejects an invalid git user", func() {
+			d.GitUser = "not an address"
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("auth", func() {
+		It("refuses private keys with passphrases", func() {
+			_, key, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			block, err := ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte("secret"))
+			Expect(err).NotTo(HaveOccurred())
+
+			d.URI = "git@example.com:some/repo.git"
+			d.PrivateKey = string(pem.EncodeToMemory(block))
+
+			_, err = d.Check(nil)
+			Expect(err).To(Equal(driver.ErrEncryptedKey))
+		})
+	})
+})
+
+// hookedTransport serves a remote, counting fetches and calling
+// beforeReceivePack before every push is received.
+type hookedTransport struct {
+	gittransport.Transport
+
+	fetches           int
+	beforeReceivePack func() error
+}
+
+func (t *hookedTransport) NewUploadPackSession(ep *gittransport.Endpoint, auth gittransport.AuthMethod) (gittransport.UploadPackSession, error) {
+	t.fetches++
+	return t.Transport.NewUploadPackSession(ep, auth)
+}
+
+func (t *hookedTransport) NewReceivePackSession(ep *gittransport.Endpoint, auth gittransport.AuthMethod) (gittransport.ReceivePackSession, error) {
+	if t.beforeReceivePack != nil {
+		err := t.beforeReceivePack()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t.Transport.NewReceivePackSession(ep, auth)
+}
+
+// newBareRepo creates an empty bare repository to act as the remote.
+func newBareRepo() string {
+	dir, err := os.MkdirTemp("", "semver-git-remote")
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(os.RemoveAll, dir)
+
+	_, err = git.PlainInit(dir, true)
+	Expect(err).NotTo(HaveOccurred())
+
+	return dir
+}
+
+// commitToBranch writes a file on the branch of the remote, creating the
+// branch if needed.
+func commitToBranch(remote, branch, file, contents string) {
+	repo, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{
+		URL:           remote,
+		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		SingleBranch:  true,
+	})
+	if err != nil {
+		repo, err = git.Init(memory.NewStorage(), memfs.New())
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(branch)))
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	worktree, err := repo.Worktree()
+	Expect(err).NotTo(HaveOccurred())
+
+	err = util.WriteFile(worktree.Filesystem, file, []byte(contents), 0644)
+	Expect(err).NotTo(HaveOccurred())
+
+	_, err = worktree.Add(file)
+	Expect(err).NotTo(HaveOccurred())
+
+	_, err = worktree.Commit("test commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	ref := plumbing.NewBranchReferenceName(branch)
+	err = repo.Push(&git.PushOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec(ref + ":" + ref)},
+	})
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func headOfBranch(remote, branch string) *object.Commit {
+	repo, err := git.PlainOpen(remote)
+	Expect(err).NotTo(HaveOccurred())
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	Expect(err).NotTo(HaveOccurred())
+
+	commit, err := repo.CommitObject(ref.Hash())
+	Expect(err).NotTo(HaveOccurred())
+
+	return commit
+}
+
+func fileOnBranch(remote, branch, file string) string {
+	f, err := headOfBranch(remote, branch).File(file)
+	Expect(err).NotTo(HaveOccurred())
+
+	contents, err := f.Contents()
+	Expect(err).NotTo(HaveOccurred())
+
+	return contents
+}
@@ -0,0 +1,199 @@
+package driver
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+)
+
+// HTTPDriver stores the version in a file that is read with GET and written
+// with PUT, e.g. on a WebDAV server or a raw/generic artifact repository.
+type HTTPDriver struct {
+	InitialVersion semver.Version
+
+	Client      *http.Client
+	URL         string
+	Username    string
+	Password    string
+	BearerToken string
+	Headers     map[string]string
+}
+
+// NewHTTPClient returns a client trusting the CA certificate from the source,
+// if any, on top of the system's.
+func NewHTTPClient(source models.Source) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: source.SkipSSLVerification}
+
+	if source.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM([]byte(source.CACert)) {
+			return nil, fmt.Errorf("no certificates found in ca_cert")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
+
+type httpStatusError struct {
+	method     string
+	url        string
+	statusCode int
+	body       string
+}
+
+// maxErrorBodyLength caps how much of a response body ends up in an error, as
+// servers may answer with a whole HTML page.
+const maxErrorBodyLength = 512
+
+func (e *httpStatusError) Error() string {
+	body := e.body
+	if len(body) > maxErrorBodyLength {
+		body = body[:maxErrorBodyLength] + "..."
+	}
+
+	return fmt.Sprintf("%s %s: unexpected status %d: %s", e.method, e.url, e.statusCode, body)
+}
+
+func (d *HTTPDriver) Bump(b version.Bump) (semver.Version, error) {
+	read := func() (semver.Version, map[string]string, error) {
+		currentVersion, etag, exists, err := d.readVersion()
+		if err != nil {
+			return semver.Version{}, nil, err
+		}
+
+		// only write if nobody else has written since we read, otherwise
+		// re-read and re-apply the bump
+		headers := map[string]string{}
+		if !exists {
+			currentVersion = d.InitialVersion
+			headers["If-None-Match"] = "*"
+		} else if strings.HasPrefix(etag, "W/") {
+			// If-Match only matches strong ETags, so it would always fail
+			fmt.Fprintf(os.Stderr, "server returned a weak ETag for %s, writing unconditionally\n", d.URL)
+		} else if etag != "" {
+			headers["If-Match"] = etag
+		}
+
+		return currentVersion, headers, nil
+	}
+
+	return retryOnConflict(b, read, d.writeVersion, isHTTPPreconditionFailed)
+}
+
+func (d *HTTPDriver) Set(newVersion semver.Version) error {
+	return d.writeVersion(newVersion, nil)
+}
+
+func (d *HTTPDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	v, _, exists, err := d.readVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		if cursor == nil {
+			return []semver.Version{d.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	}
+
+	// the file keeps no history, so only the current version can be reported
+	return []semver.Version{v}, nil
+}
+
+// readVersion returns the version in the file along with its ETag, which is
+// empty if the server does not provide one.
+func (d *HTTPDriver) readVersion() (semver.Version, string, bool, error) {
+	resp, err := d.do(http.MethodGet, nil, nil)
+	if err != nil {
+		return semver.Version{}, "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return semver.Version{}, "", false, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return semver.Version{}, "", false, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return semver.Version{}, "", false, &httpStatusError{http.MethodGet, d.URL, resp.StatusCode, string(body)}
+	}
+
+	v, err := semver.Parse(strings.TrimSpace(string(body)))
+	if err != nil {
+		return semver.Version{}, "", false, fmt.Errorf("parsing number in %s: %s", d.URL, err)
+	}
+
+	return v, resp.Header.Get("ETag"), true, nil
+}
+
+func (d *HTTPDriver) writeVersion(newVersion semver.Version, headers map[string]string) error {
+	resp, err := d.do(http.MethodPut, []byte(newVersion.String()), headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(resp.Body)
+		return &httpStatusError{http.MethodPut, d.URL, resp.StatusCode, string(body)}
+	}
+
+	return nil
+}
+
+func isHTTPPreconditionFailed(err error) bool {
+	var statusErr *httpStatusError
+	return errors.As(err, &statusErr) && statusErr.statusCode == http.StatusPreconditionFailed
+}
+
+func (d *HTTPDriver) do(method string, body []byte, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "text/plain")
+	}
+
+	for name, value := range d.Headers {
+		req.Header.Set(name, value)
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	if d.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+d.BearerToken)
+	} else if d.Username != "" || d.Password != "" {
+		req.SetBasicAuth(d.Username, d.Password)
+	}
+
+	return d.Client.Do(req)
+}
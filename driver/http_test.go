@@ -0,0 +1,321 @@
+package driver_test
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP Driver", func() {
+	var (
+		store *fakeFileServer
+		d     *driver.HTTPDriver
+	)
+
+	BeforeEach(func() {
+		d, store = newFakeHTTPDriver()
+	})
+
+	Describe("Check", func() {
+		It("returns the initial version when the file does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+			Expect(store.paths).To(ConsistOf("/repository/versions/some-version"))
+		})
+
+		It("returns no versions for a cursor when the file does not exist", func() {
+			cursor := semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(BeEmpty())
+		})
+
+		It("returns the version in the file", func() {
+			store.put("2.3.4\n")
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("returns an error on an unexpected status", func() {
+			store.status = http.StatusUnauthorized
+
+			_, err := d.Check(nil)
+			Expect(err).To(MatchError(ContainSubstring("unexpected status 401")))
+		})
+
+		It("sends basic auth credentials and the configured headers", func() {
+			d.Username = "semver"
+			d.Password = "secret"
+			d.Headers = map[string]string{"X-Team": "release"}
+
+			_, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			username, password, ok := store.requests[0].BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(username).To(Equal("semver"))
+			Expect(password).To(Equal("secret"))
+			Expect(store.requests[0].Header.Get("X-Team")).To(Equal("release"))
+		})
+
+		It("sends a bearer token instead of basic auth", func() {
+			d.Username = "semver"
+			d.BearerToken = "some-token"
+
+			_, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.requests[0].Header.Get("Authorization")).To(Equal("Bearer some-token"))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("creates the file only if it does not exist yet", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(store.body).To(Equal("1.1.0"))
+			Expect(store.conditions).To(Equal([]string{"If-None-Match: *"}))
+		})
+
+		It("writes with the ETag that was read", func() {
+			store.put("1.2.3")
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(store.body).To(Equal("1.2.4"))
+			Expect(store.conditions).To(Equal([]string{`If-Match: "1"`}))
+		})
+
+		It("writes unconditionally when the server does not provide ETags", func() {
+			store.noETags = true
+			store.put("1.2.3")
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(store.conditions).To(Equal([]string{""}))
+		})
+
+		It("writes unconditionally when the server provides weak ETags", func() {
+			store.weakETags = true
+			store.put("1.2.3")
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(store.body).To(Equal("1.2.4"))
+			Expect(store.conditions).To(Equal([]string{""}))
+		})
+
+		It("truncates long response bodies in errors", func() {
+			store.put("1.2.3")
+			store.putStatus = http.StatusForbidden
+			store.putBody = strings.Repeat("x", 1000)
+
+			_, err := d.Bump(version.PatchBump{})
+			Expect(err).To(MatchError(ContainSubstring("unexpected status 403: " + strings.Repeat("x", 512) + "...")))
+			Expect(err.Error()).NotTo(ContainSubstring(strings.Repeat("x", 513)))
+		})
+
+		It("returns an error when the write is rejected", func() {
+			store.put("1.2.3")
+			store.putStatus = http.StatusForbidden
+
+			_, err := d.Bump(version.PatchBump{})
+			Expect(err).To(MatchError(ContainSubstring("PUT " + d.URL + ": unexpected status 403")))
+		})
+	})
+
+	Describe("Set", func() {
+		It("writes the file unconditionally", func() {
+			store.put("1.2.3")
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.body).To(Equal("5.0.0"))
+			Expect(store.conditions).To(Equal([]string{""}))
+		})
+	})
+
+	Describe("NewHTTPClient", func() {
+		var tlsServer *httptest.Server
+
+		BeforeEach(func() {
+			tlsServer = httptest.NewTLSServer(store)
+			DeferCleanup(tlsServer.Close)
+
+			store.put("2.3.4")
+		})
+
+		check := func(source models.Source) error {
+			client, err := driver.NewHTTPClient(source)
+			Expect(err).NotTo(HaveOccurred())
+
+			d.Client = client
+			d.URL = tlsServer.URL + "/some-version"
+
+			_, err = d.Check(nil)
+			return err
+		}
+
+		It("trusts the given CA certificate", func() {
+			caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+
+			Expect(check(models.Source{CACert: string(caCert)})).To(Succeed())
+		})
+
+		It("can skip certificate verification", func() {
+			Expect(check(models.Source{SkipSSLVerification: true})).To(Succeed())
+		})
+
+		It("verifies the certificate by default", func() {
+			Expect(check(models.Source{})).To(MatchError(ContainSubstring("certificate")))
+		})
+
+		It("returns an error when the CA certificate is invalid", func() {
+			_, err := driver.NewHTTPClient(models.Source{CACert: "bogus"})
+			Expect(err).To(MatchError("no certificates found in ca_cert"))
+		})
+	})
+})
+
+// fakeFileServer serves a single file, honouring If-Match and If-None-Match
+// like a WebDAV server. Each entry of concurrentWrites is written by "another
+// writer" right before a PUT is handled.
+type fakeFileServer struct {
+	body      string
+	exists    bool
+	etag      int
+	noETags   bool
+	weakETags bool
+
+	status    int
+	putStatus int
+	putBody   string
+
+	concurrentWrites []string
+	conditions       []string
+	paths            []string
+	requests         []*http.Request
+}
+
+func (s *fakeFileServer) put(body string) {
+	s.body = body
+	s.exists = true
+	s.etag++
+}
+
+func (s *fakeFileServer) currentETag() string {
+	if s.weakETags {
+		return fmt.Sprintf(`W/"%d"`, s.etag)
+	}
+
+	return fmt.Sprintf(`"%d"`, s.etag)
+}
+
+func (s *fakeFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.paths = append(s.paths, r.URL.Path)
+	s.requests = append(s.requests, r)
+
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !s.exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if !s.noETags {
+			w.Header().Set("ETag", s.currentETag())
+		}
+		io.WriteString(w, s.body)
+
+	case http.MethodPut:
+		ifMatch := r.Header.Get("If-Match")
+		ifNoneMatch := r.Header.Get("If-None-Match")
+
+		switch {
+		case ifMatch != "":
+			s.conditions = append(s.conditions, "If-Match: "+ifMatch)
+		case ifNoneMatch != "":
+			s.conditions = append(s.conditions, "If-None-Match: "+ifNoneMatch)
+		default:
+			s.conditions = append(s.conditions, "")
+		}
+
+		if s.putStatus != 0 {
+			w.WriteHeader(s.putStatus)
+			io.WriteString(w, s.putBody)
+			return
+		}
+
+		if len(s.concurrentWrites) > 0 {
+			s.put(s.concurrentWrites[0])
+			s.concurrentWrites = s.concurrentWrites[1:]
+		}
+
+		if (ifNoneMatch == "*" && s.exists) || (ifMatch != "" && (!s.exists || ifMatch != s.currentETag())) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		status := http.StatusNoContent
+		if !s.exists {
+			status = http.StatusCreated
+		}
+
+		s.put(string(body))
+		w.WriteHeader(status)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeHTTPDriver() (*driver.HTTPDriver, *fakeFileServer) {
+	store := &fakeFileServer{}
+	server := httptest.NewServer(store)
+	DeferCleanup(server.Close)
+
+	return &driver.HTTPDriver{
+		InitialVersion: semver.Version{Major: 1},
+		Client:         http.DefaultClient,
+		URL:            server.URL + "/repository/versions/some-version",
+	}, store
+}
+
+func httpCASSubject() casSubject {
+	d, store := newFakeHTTPDriver()
+
+	return casSubject{
+		driver:  d,
+		store:   store.put,
+		current: func() string { return store.body },
+		race:    func(versions ...string) { store.concurrentWrites = versions },
+	}
+}
@@ -0,0 +1,48 @@
+package driver
+
+import (
+	"fmt"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/version"
+)
+
+// IncreasingDriver wraps a driver to refuse writing a version that is not
+// strictly greater than the current one, e.g. when a stale version file is
+// put. The version is checked against the one the wrapped driver bumps, which
+// is the initial version when none is stored yet, so writing the initial
+// version itself is refused too.
+//
+// Set is made a bump of the wrapped driver so that the check is made against
+// the version that is replaced, even if another write was made since the
+// version was last read. An IdentityBump is let through, as it deliberately
+// keeps the current version.
+type IncreasingDriver struct {
+	Driver Driver
+}
+
+func (driver *IncreasingDriver) Bump(bump version.Bump) (semver.Version, error) {
+	if _, ok := bump.(version.IdentityBump); ok {
+		return driver.Driver.Bump(bump)
+	}
+
+	return driver.Driver.Bump(version.GuardBump{Bump: bump, Guard: checkIncreasing})
+}
+
+func (driver *IncreasingDriver) Set(newVersion semver.Version) error {
+	_, err := driver.Bump(version.SetBump{Version: newVersion})
+	return err
+}
+
+func (driver *IncreasingDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	return driver.Driver.Check(cursor)
+}
+
+func checkIncreasing(currentVersion semver.Version, newVersion semver.Version) error {
+	if newVersion.GT(currentVersion) {
+		return nil
+	}
+
+	return fmt.Errorf("refusing to write version %s: it is not greater than the current version %s", newVersion, currentVersion)
+}
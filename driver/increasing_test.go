@@ -0,0 +1,127 @@
+package driver_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Increasing Driver", func() {
+	var (
+		path string
+		d    *driver.IncreasingDriver
+	)
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "version")
+		Expect(os.WriteFile(path, []byte("2.0.0\n"), 0644)).To(Succeed())
+
+		d = &driver.IncreasingDriver{
+			Driver: &driver.FileDriver{
+				InitialVersion: semver.Version{Major: 1},
+				Path:           path,
+			},
+		}
+	})
+
+	current := func() string {
+		contents, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	Describe("Check", func() {
+		It("returns the version of the wrapped driver", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("bumps to a greater version", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("2.1.0"))
+			Expect(current()).To(Equal("2.1.0\n"))
+		})
+
+		It("refuses a bump that leaves the version as it is", func() {
+			_, err := d.Bump(version.FinalBump{})
+			Expect(err).To(MatchError("refusing to write version 2.0.0: it is not greater than the current version 2.0.0"))
+			Expect(current()).To(Equal("2.0.0\n"))
+		})
+
+		It("keeps the version for an identity bump", func() {
+			newVersion, err := d.Bump(version.IdentityBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("2.0.0"))
+			Expect(current()).To(Equal("2.0.0\n"))
+		})
+
+		It("refuses a bump to a lower version", func() {
+			_, err := d.Bump(version.PreBump{Pre: "rc"})
+			Expect(err).To(MatchError("refusing to write version 2.0.0-rc.1: it is not greater than the current version 2.0.0"))
+			Expect(current()).To(Equal("2.0.0\n"))
+		})
+	})
+
+	Describe("Set", func() {
+		It("sets a greater version", func() {
+			err := d.Set(semver.MustParse("2.0.1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current()).To(Equal("2.0.1\n"))
+		})
+
+		It("refuses a lower version", func() {
+			err := d.Set(semver.MustParse("1.0.0"))
+			Expect(err).To(MatchError("refusing to write version 1.0.0: it is not greater than the current version 2.0.0"))
+			Expect(current()).To(Equal("2.0.0\n"))
+		})
+
+		It("refuses the current version", func() {
+			err := d.Set(semver.MustParse("2.0.0"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("ignores build metadata, which has no precedence", func() {
+			err := d.Set(semver.MustParse("2.0.0+ci.1"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("compares with the initial version when none is stored", func() {
+			Expect(os.Remove(path)).To(Succeed())
+
+			err := d.Set(semver.MustParse("0.9.0"))
+			Expect(err).To(MatchError("refusing to write version 0.9.0: it is not greater than the current version 1.0.0"))
+
+			err = d.Set(semver.MustParse("1.0.1"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("refuses the initial version itself when none is stored", func() {
+			Expect(os.Remove(path)).To(Succeed())
+
+			err := d.Set(semver.MustParse("1.0.0"))
+			Expect(err).To(MatchError("refusing to write version 1.0.0: it is not greater than the current version 1.0.0"))
+			Expect(path).NotTo(BeAnExistingFile())
+		})
+
+		It("compares with the version it replaces after losing a race", func() {
+			consul, store := newFakeConsulDriver()
+			store.put("2.0.0")
+			store.concurrentWrites = []string{"3.0.0"}
+			d.Driver = consul
+
+			err := d.Set(semver.MustParse("2.5.0"))
+			Expect(err).To(MatchError("refusing to write version 2.5.0: it is not greater than the current version 3.0.0"))
+			Expect(store.value).To(Equal("3.0.0"))
+		})
+	})
+})
@@ -0,0 +1,158 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+)
+
+// KubernetesDriver stores the version under a key of a ConfigMap. Other keys
+// of the ConfigMap are left as they are.
+type KubernetesDriver struct {
+	InitialVersion semver.Version
+
+	Client        kubernetes.Interface
+	Namespace     string
+	ConfigMapName string
+	Key           string
+}
+
+// NewKubernetesClient uses the kubeconfig from the source if given, and the
+// pod's service account otherwise.
+func NewKubernetesClient(source models.Source) (kubernetes.Interface, error) {
+	var config *rest.Config
+	var err error
+
+	if source.Kubeconfig != "" {
+		config, err = clientcmd.RESTConfigFromKubeConfig([]byte(source.Kubeconfig))
+		if err != nil {
+			return nil, fmt.Errorf("loading kubeconfig: %w", err)
+		}
+	} else {
+		config, err = rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("loading in-cluster config: %w", err)
+		}
+	}
+
+	return kubernetes.NewForConfig(config)
+}
+
+// Bump writes the bumped version. The ConfigMap is created if it does not
+// exist; otherwise it is updated with the resourceVersion that was read, so a
+// concurrent write makes it fail and start over.
+func (d *KubernetesDriver) Bump(b version.Bump) (semver.Version, error) {
+	read := func() (semver.Version, *corev1.ConfigMap, error) {
+		configMap, err := d.get()
+		if err != nil {
+			return semver.Version{}, nil, err
+		}
+
+		v, exists, err := d.parseVersion(configMap)
+		if err == nil && !exists {
+			v = d.InitialVersion
+		}
+
+		return v, configMap, err
+	}
+
+	return retryOnConflict(b, read, d.write, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	})
+}
+
+func (d *KubernetesDriver) Set(v semver.Version) error {
+	_, err := d.Bump(version.SetBump{Version: v})
+	return err
+}
+
+func (d *KubernetesDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	configMap, err := d.get()
+	if err != nil {
+		return nil, err
+	}
+
+	v, exists, err := d.parseVersion(configMap)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		if cursor == nil {
+			return []semver.Version{d.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	}
+
+	// the ConfigMap keeps no history, so only the current version can be reported
+	return []semver.Version{v}, nil
+}
+
+// write creates the ConfigMap with the version if it was not read, and
+// updates the ConfigMap that was read otherwise.
+func (d *KubernetesDriver) write(newVersion semver.Version, configMap *corev1.ConfigMap) error {
+	ctx := context.Background()
+
+	if configMap == nil {
+		_, err := d.Client.CoreV1().ConfigMaps(d.Namespace).Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      d.ConfigMapName,
+				Namespace: d.Namespace,
+			},
+			Data: map[string]string{d.Key: newVersion.String()},
+		}, metav1.CreateOptions{})
+		return err
+	}
+
+	configMap = configMap.DeepCopy()
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[d.Key] = newVersion.String()
+
+	_, err := d.Client.CoreV1().ConfigMaps(d.Namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
+
+// get returns the ConfigMap, or nil if it does not exist.
+func (d *KubernetesDriver) get() (*corev1.ConfigMap, error) {
+	configMap, err := d.Client.CoreV1().ConfigMaps(d.Namespace).Get(context.Background(), d.ConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return configMap, nil
+}
+
+func (d *KubernetesDriver) parseVersion(configMap *corev1.ConfigMap) (semver.Version, bool, error) {
+	if configMap == nil {
+		return semver.Version{}, false, nil
+	}
+
+	value, found := configMap.Data[d.Key]
+	if !found {
+		return semver.Version{}, false, nil
+	}
+
+	v, err := semver.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return semver.Version{}, false, fmt.Errorf("parsing number in key %s of config map %s: %s", d.Key, d.ConfigMapName, err)
+	}
+
+	return v, true, nil
+}
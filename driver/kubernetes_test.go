@@ -0,0 +1,201 @@
+package driver_test
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/blang/semver"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kubernetes Driver", func() {
+	var (
+		configMaps *fakeConfigMaps
+		d          *driver.KubernetesDriver
+	)
+
+	BeforeEach(func() {
+		d, configMaps = newFakeKubernetesDriver()
+	})
+
+	create := func(data map[string]string) {
+		_, err := configMaps.clientset.CoreV1().ConfigMaps("ci").Create(context.Background(), &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: "ci"},
+			Data:       data,
+		}, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	current := func() map[string]string {
+		configMap, err := configMaps.clientset.CoreV1().ConfigMaps("ci").Get(context.Background(), "release", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return configMap.Data
+	}
+
+	Describe("Check", func() {
+		It("returns the initial version when the config map does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the initial version when the key does not exist", func() {
+			create(map[string]string{"owner": "release-team"})
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the version in the key", func() {
+			create(map[string]string{"version": "2.3.4"})
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("creates the config map when it does not exist", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(current()).To(Equal(map[string]string{"version": "1.1.0"}))
+		})
+
+		It("keeps the other keys of the config map", func() {
+			create(map[string]string{"owner": "release-team", "version": "1.2.3"})
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(current()).To(Equal(map[string]string{"owner": "release-team", "version": "1.2.4"}))
+		})
+	})
+
+	Describe("Set", func() {
+		It("creates the config map when it does not exist", func() {
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current()).To(Equal(map[string]string{"version": "5.0.0"}))
+		})
+
+		It("updates the key", func() {
+			create(map[string]string{"version": "1.2.3"})
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current()).To(Equal(map[string]string{"version": "5.0.0"}))
+		})
+	})
+})
+
+// fakeConfigMaps makes the config maps of a fake clientset conflict the way
+// the API server would, as the fake clientset neither sets nor checks
+// resourceVersion. Each entry of concurrentWrites is written to the version
+// key by "another pipeline" right before the next create or update.
+type fakeConfigMaps struct {
+	clientset *fake.Clientset
+
+	concurrentWrites []string
+	resourceVersions int
+}
+
+func newFakeKubernetesDriver() (*driver.KubernetesDriver, *fakeConfigMaps) {
+	configMaps := &fakeConfigMaps{clientset: fake.NewClientset()}
+	configMaps.clientset.PrependReactor("create", "configmaps", configMaps.create)
+	configMaps.clientset.PrependReactor("update", "configmaps", configMaps.update)
+
+	return &driver.KubernetesDriver{
+		InitialVersion: semver.Version{Major: 1},
+		Client:         configMaps.clientset,
+		Namespace:      "ci",
+		ConfigMapName:  "release",
+		Key:            "version",
+	}, configMaps
+}
+
+func (c *fakeConfigMaps) put(value string) {
+	gvr := corev1.SchemeGroupVersion.WithResource("configmaps")
+
+	c.resourceVersions++
+	existing, err := c.clientset.Tracker().Get(gvr, "ci", "release")
+	if apierrors.IsNotFound(err) {
+		err = c.clientset.Tracker().Add(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: "ci", ResourceVersion: strconv.Itoa(c.resourceVersions)},
+			Data:       map[string]string{"version": value},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return
+	}
+	Expect(err).NotTo(HaveOccurred())
+
+	configMap := existing.(*corev1.ConfigMap).DeepCopy()
+	configMap.ResourceVersion = strconv.Itoa(c.resourceVersions)
+	configMap.Data["version"] = value
+
+	err = c.clientset.Tracker().Update(gvr, configMap, "ci")
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func (c *fakeConfigMaps) current() string {
+	configMap, err := c.clientset.CoreV1().ConfigMaps("ci").Get(context.Background(), "release", metav1.GetOptions{})
+	Expect(err).NotTo(HaveOccurred())
+	return configMap.Data["version"]
+}
+
+func (c *fakeConfigMaps) writeConcurrently() {
+	if len(c.concurrentWrites) > 0 {
+		c.put(c.concurrentWrites[0])
+		c.concurrentWrites = c.concurrentWrites[1:]
+	}
+}
+
+func (c *fakeConfigMaps) create(action k8stesting.Action) (bool, runtime.Object, error) {
+	c.writeConcurrently()
+
+	// let the tracker refuse to create the config map if it exists
+	return false, nil, nil
+}
+
+func (c *fakeConfigMaps) update(action k8stesting.Action) (bool, runtime.Object, error) {
+	update := action.(k8stesting.UpdateAction).GetObject().(*corev1.ConfigMap)
+
+	c.writeConcurrently()
+
+	existing, err := c.clientset.Tracker().Get(corev1.SchemeGroupVersion.WithResource("configmaps"), update.Namespace, update.Name)
+	if err != nil {
+		return true, nil, err
+	}
+
+	if existing.(*corev1.ConfigMap).ResourceVersion != update.ResourceVersion {
+		return true, nil, apierrors.NewConflict(corev1.Resource("configmaps"), update.Name, nil)
+	}
+
+	// let the tracker store the update under a new resourceVersion
+	c.resourceVersions++
+	update.ResourceVersion = strconv.Itoa(c.resourceVersions)
+	return false, nil, nil
+}
+
+func kubernetesCASSubject() casSubject {
+	d, configMaps := newFakeKubernetesDriver()
+
+	return casSubject{
+		driver:  d,
+		store:   configMaps.put,
+		current: configMaps.current,
+		race:    func(versions ...string) { configMaps.concurrentWrites = versions },
+	}
+}
@@ -0,0 +1,232 @@
+package driver
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+)
+
+// OCIDriver stores versions as tags of a repository in a container registry.
+// The current version is the highest tag that parses as semver once
+// TagPrefix is removed. New versions are recorded by tagging the manifest of
+// FromTag, or by pushing a tiny artifact when FromTag is empty.
+type OCIDriver struct {
+	InitialVersion semver.Version
+
+	Repository name.Repository
+	TagPrefix  string
+	FromTag    string
+	Options    []remote.Option
+}
+
+var errOCITagExists = errors.New("tag already exists")
+
+// NewOCIRepository parses the repository and returns the options to access
+// it with, authenticating with the username and password from the source if
+// given.
+func NewOCIRepository(source models.Source) (name.Repository, []remote.Option, error) {
+	var nameOpts []name.Option
+	if source.DisableSSL {
+		nameOpts = append(nameOpts, name.Insecure)
+	}
+
+	repository, err := name.NewRepository(source.Repository, nameOpts...)
+	if err != nil {
+		return name.Repository{}, nil, fmt.Errorf("parsing repository: %w", err)
+	}
+
+	auth := authn.Anonymous
+	if source.Username != "" || source.Password != "" {
+		auth = &authn.Basic{Username: source.Username, Password: source.Password}
+	}
+
+	options := []remote.Option{remote.WithAuth(auth)}
+
+	if source.SkipSSLVerification {
+		transport := remote.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		options = append(options, remote.WithTransport(transport))
+	}
+
+	return repository, options, nil
+}
+
+func (driver *OCIDriver) Bump(bump version.Bump) (semver.Version, error) {
+	var newVersion semver.Version
+	var err error
+
+	for range RetriesOnErrorWriteVersion {
+		var versions []semver.Version
+		versions, err = driver.listVersions()
+		if err != nil {
+			return semver.Version{}, err
+		}
+
+		currentVersion := driver.InitialVersion
+		if len(versions) > 0 {
+			currentVersion = versions[len(versions)-1]
+		}
+
+		newVersion, err = version.Apply(bump, currentVersion)
+		if err != nil {
+			return semver.Version{}, err
+		}
+
+		// the current version is already tagged, unless it is the initial
+		// version of a repository without version tags
+		if len(versions) > 0 && newVersion.String() == currentVersion.String() {
+			fmt.Fprintf(os.Stderr, "Tag %s already exists, skipping version push\n", driver.tagName(newVersion))
+			return newVersion, nil
+		}
+
+		// a concurrent bump may have pushed the tag since the tags were
+		// listed, in which case they are listed again and the bump re-applied
+		err = driver.pushTag(newVersion)
+		if !errors.Is(err, errOCITagExists) {
+			break
+		}
+	}
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	return newVersion, nil
+}
+
+func (driver *OCIDriver) Set(newVersion semver.Version) error {
+	err := driver.pushTag(newVersion)
+	if errors.Is(err, errOCITagExists) {
+		fmt.Fprintf(os.Stderr, "Tag %s already exists, skipping version push\n", driver.tagName(newVersion))
+		return nil
+	}
+
+	return err
+}
+
+func (driver *OCIDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	versions, err := driver.listVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(versions) == 0 {
+		if cursor == nil {
+			return []semver.Version{driver.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	}
+
+	currentVersion := versions[len(versions)-1]
+
+	// Supplied cursor version is newer or equal to current, so only the
+	// current version is relevant
+	if cursor == nil || cursor.GTE(currentVersion) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	// Handle a "fly check-resource --from <cursor>" by returning every tag
+	// from the cursor onwards
+	i, _ := slices.BinarySearchFunc(versions, *cursor, semver.Version.Compare)
+	return versions[i:], nil
+}
+
+func (driver *OCIDriver) tagName(v semver.Version) string {
+	// tags cannot contain "+", which registries conventionally replace with "_"
+	return driver.TagPrefix + strings.ReplaceAll(v.String(), "+", "_")
+}
+
+// listVersions returns the versions of all tags of the repository that start
+// with the prefix and parse as semver, in ascending order.
+func (driver *OCIDriver) listVersions() ([]semver.Version, error) {
+	tags, err := remote.List(driver.Repository, driver.Options...)
+	if err != nil {
+		// a repository that was never pushed to has no tags yet
+		if isOCINotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("listing tags of %s: %w", driver.Repository, err)
+	}
+
+	var versions []semver.Version
+	for _, tag := range tags {
+		tag, found := strings.CutPrefix(tag, driver.TagPrefix)
+		if !found {
+			continue
+		}
+
+		v, err := semver.Parse(strings.ReplaceAll(tag, "_", "+"))
+		if err != nil {
+			continue
+		}
+
+		versions = append(versions, v)
+	}
+
+	semver.Sort(versions)
+	versions = slices.CompactFunc(versions, semver.Version.Equals)
+
+	return versions, nil
+}
+
+// pushTag tags the manifest of FromTag, or an artifact recording the version,
+// with the version unless the tag already exists. Registries cannot create a
+// tag only if it is absent, so two writers checking at the same moment can
+// still both push it.
+func (driver *OCIDriver) pushTag(newVersion semver.Version) error {
+	tag := driver.Repository.Tag(driver.tagName(newVersion))
+
+	_, err := remote.Head(tag, driver.Options...)
+	if err == nil {
+		return errOCITagExists
+	}
+
+	if !isOCINotFound(err) {
+		return fmt.Errorf("looking up tag %s: %w", tag, err)
+	}
+
+	if driver.FromTag != "" {
+		from := driver.Repository.Tag(driver.FromTag)
+
+		desc, err := remote.Get(from, driver.Options...)
+		if err != nil {
+			return fmt.Errorf("getting manifest of %s: %w", from, err)
+		}
+
+		return remote.Tag(tag, desc, driver.Options...)
+	}
+
+	return remote.Write(tag, versionArtifact(newVersion), driver.Options...)
+}
+
+// versionArtifact is an empty image annotated with the version.
+func versionArtifact(v semver.Version) v1.Image {
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, types.OCIConfigJSON)
+
+	return mutate.Annotations(img, map[string]string{
+		"org.opencontainers.image.version": v.String(),
+	}).(v1.Image)
+}
+
+func isOCINotFound(err error) bool {
+	var transportErr *transport.Error
+	return errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,196 @@
+package driver_test
+
+import (
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OCI Driver", func() {
+	var (
+		server *httptest.Server
+		d      *driver.OCIDriver
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+		DeferCleanup(server.Close)
+
+		repository, options, err := driver.NewOCIRepository(models.Source{
+			Repository: strings.TrimPrefix(server.URL, "http://") + "/team/app",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		d = &driver.OCIDriver{
+			InitialVersion: semver.Version{Major: 1},
+			Repository:     repository,
+			TagPrefix:      "v",
+			Options:        options,
+		}
+	})
+
+	pushImage := func(tags ...string) {
+		img, err := random.Image(64, 1)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, tag := range tags {
+			Expect(remote.Write(d.Repository.Tag(tag), img)).To(Succeed())
+		}
+	}
+
+	tags := func() []string {
+		tags, err := remote.List(d.Repository)
+		Expect(err).NotTo(HaveOccurred())
+		return tags
+	}
+
+	digest := func(tag string) string {
+		desc, err := remote.Head(d.Repository.Tag(tag))
+		Expect(err).NotTo(HaveOccurred())
+		return desc.Digest.String()
+	}
+
+	Describe("Check", func() {
+		It("returns the initial version when the repository does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns no versions for a cursor when there are no version tags", func() {
+			pushImage("latest")
+
+			cursor := semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(BeEmpty())
+		})
+
+		It("returns the highest tag with the prefix that parses as semver", func() {
+			pushImage("v1.2.3", "v1.10.0", "v1.9.0", "latest", "2.0.0", "vbogus")
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.10.0")}))
+		})
+
+		It("returns the tags from the cursor onwards in order", func() {
+			pushImage("v1.2.3", "v1.10.0", "v1.9.0", "v1.3.0-rc.1", "v1.3.0_build.5")
+
+			cursor := semver.MustParse("1.3.0-rc.1")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.3.0-rc.1"),
+				semver.MustParse("1.3.0+build.5"),
+				semver.MustParse("1.9.0"),
+				semver.MustParse("1.10.0"),
+			}))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("pushes an artifact tagged with the bumped initial version", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(tags()).To(ConsistOf("v1.1.0"))
+
+			manifest, err := remote.Get(d.Repository.Tag("v1.1.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(manifest.Manifest)).To(ContainSubstring(`"org.opencontainers.image.version":"1.1.0"`))
+		})
+
+		It("bumps the highest version", func() {
+			pushImage("v1.2.3", "v1.10.0")
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.10.1"))
+			Expect(tags()).To(ConsistOf("v1.2.3", "v1.10.0", "v1.10.1"))
+		})
+
+		It("tags the manifest of the configured tag", func() {
+			pushImage("latest")
+			d.FromTag = "latest"
+
+			newVersion, err := d.Bump(version.MajorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("2.0.0"))
+			Expect(digest("v2.0.0")).To(Equal(digest("latest")))
+		})
+
+		It("keeps the version when the bump leaves it as it is", func() {
+			pushImage("v1.2.3")
+			before := digest("v1.2.3")
+
+			newVersion, err := d.Bump(version.FinalBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.3"))
+			Expect(tags()).To(ConsistOf("v1.2.3"))
+			Expect(digest("v1.2.3")).To(Equal(before))
+		})
+
+		It("tags the initial version when the bump leaves it as it is", func() {
+			newVersion, err := d.Bump(version.IdentityBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.0.0"))
+			Expect(tags()).To(ConsistOf("v1.0.0"))
+		})
+
+		It("tags build metadata with an underscore", func() {
+			pushImage("v1.2.3")
+
+			newVersion, err := d.Bump(version.BuildBump{Build: "ci.7"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.3+ci.7.1"))
+			Expect(tags()).To(ContainElement("v1.2.3_ci.7.1"))
+		})
+	})
+
+	Describe("Set", func() {
+		It("pushes the tag", func() {
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tags()).To(ConsistOf("v5.0.0"))
+		})
+
+		It("leaves an existing tag alone", func() {
+			pushImage("v5.0.0")
+			before := digest("v5.0.0")
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(digest("v5.0.0")).To(Equal(before))
+		})
+	})
+
+	Describe("NewOCIRepository", func() {
+		It("returns an error for an invalid repository", func() {
+			_, _, err := driver.NewOCIRepository(models.Source{Repository: "Not A Repository"})
+			Expect(err).To(MatchError(ContainSubstring("parsing repository")))
+		})
+
+		It("allows plain http registries when ssl is disabled", func() {
+			repository, _, err := driver.NewOCIRepository(models.Source{Repository: "registry.example.com/team/app", DisableSSL: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repository.Scheme()).To(Equal("http"))
+
+			repository, _, err = driver.NewOCIRepository(models.Source{Repository: "registry.example.com/team/app"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repository.Scheme()).To(Equal("https"))
+		})
+	})
+})
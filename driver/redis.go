@@ -0,0 +1,114 @@
+package driver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+)
+
+type RedisDriver struct {
+	InitialVersion semver.Version
+
+	Client *redis.Client
+	Key    string
+}
+
+func NewRedisClient(source models.Source) *redis.Client {
+	options := &redis.Options{
+		Addr:     source.RedisAddress,
+		DB:       source.RedisDatabase,
+		Username: source.RedisUsername,
+		Password: source.RedisPassword,
+	}
+
+	if source.RedisTLS {
+		options.TLSConfig = &tls.Config{InsecureSkipVerify: source.SkipSSLVerification}
+	}
+
+	return redis.NewClient(options)
+}
+
+func (d *RedisDriver) Bump(b version.Bump) (semver.Version, error) {
+	ctx := context.Background()
+
+	read := func() (semver.Version, *string, error) {
+		v, value, err := d.readVersion(ctx, d.Client)
+		if err == nil && value == nil {
+			v = d.InitialVersion
+		}
+
+		return v, value, err
+	}
+
+	return retryOnConflict(b, read, func(newVersion semver.Version, value *string) error {
+		// the transaction is discarded if the key changes after it is
+		// watched, so it is only written if it still holds the value read
+		return d.Client.Watch(ctx, func(tx *redis.Tx) error {
+			_, currentValue, err := d.readVersion(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			if (value == nil) != (currentValue == nil) || (value != nil && *value != *currentValue) {
+				return fmt.Errorf("key %s was %w", d.Key, errModifiedConcurrently)
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, d.Key, newVersion.String(), 0)
+				return nil
+			})
+			return err
+		}, d.Key)
+	}, func(err error) bool {
+		return errors.Is(err, redis.TxFailedErr) || isModifiedConcurrently(err)
+	})
+}
+
+func (d *RedisDriver) Set(v semver.Version) error {
+	return d.Client.Set(context.Background(), d.Key, v.String(), 0).Err()
+}
+
+func (d *RedisDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	v, value, err := d.readVersion(context.Background(), d.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		if cursor == nil {
+			return []semver.Version{d.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	}
+
+	// the key keeps no history, so only the current version can be reported
+	return []semver.Version{v}, nil
+}
+
+// readVersion returns the version in the key along with the value it was
+// parsed from, which is nil if the key does not exist.
+func (d *RedisDriver) readVersion(ctx context.Context, client redis.Cmdable) (semver.Version, *string, error) {
+	value, err := client.Get(ctx, d.Key).Result()
+	if errors.Is(err, redis.Nil) {
+		return semver.Version{}, nil, nil
+	}
+
+	if err != nil {
+		return semver.Version{}, nil, err
+	}
+
+	v, err := semver.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return semver.Version{}, nil, fmt.Errorf("parsing number in key %s: %s", d.Key, err)
+	}
+
+	return v, &value, nil
+}
@@ -0,0 +1,138 @@
+package driver_test
+
+import (
+	"context"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/blang/semver"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redis Driver", func() {
+	var (
+		server *miniredis.Miniredis
+		d      *driver.RedisDriver
+	)
+
+	BeforeEach(func() {
+		d, server = newFakeRedisDriver()
+	})
+
+	current := func() string {
+		server.Select(2)
+		value, err := server.Get("some-version")
+		Expect(err).NotTo(HaveOccurred())
+		return value
+	}
+
+	Describe("Check", func() {
+		It("returns the initial version when the key does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the version in the key of the configured database", func() {
+			server.DB(2).Set("some-version", "2.3.4")
+			server.DB(0).Set("some-version", "9.9.9")
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("returns an error when the key does not contain a version", func() {
+			server.DB(2).Set("some-version", "bogus")
+
+			_, err := d.Check(nil)
+			Expect(err).To(MatchError(ContainSubstring("parsing number in key some-version")))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("bumps the initial version when the key does not exist", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(current()).To(Equal("1.1.0"))
+		})
+	})
+
+	Describe("Set", func() {
+		It("writes the key", func() {
+			server.DB(2).Set("some-version", "1.2.3")
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current()).To(Equal("5.0.0"))
+		})
+	})
+})
+
+// concurrentWriteHook writes the key from "another client" right before each
+// MULTI/EXEC transaction is sent, until it runs out of writes.
+type concurrentWriteHook struct {
+	server *miniredis.Miniredis
+	writes []string
+}
+
+func (h *concurrentWriteHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *concurrentWriteHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (h *concurrentWriteHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if len(h.writes) > 0 && cmds[0].Name() == "multi" {
+			h.server.DB(2).Set("some-version", h.writes[0])
+			h.writes = h.writes[1:]
+		}
+
+		return next(ctx, cmds)
+	}
+}
+
+func newFakeRedisDriver() (*driver.RedisDriver, *miniredis.Miniredis) {
+	server := miniredis.RunT(GinkgoT())
+	server.RequireUserAuth("semver", "secret")
+
+	d := &driver.RedisDriver{
+		InitialVersion: semver.Version{Major: 1},
+		Client: driver.NewRedisClient(models.Source{
+			RedisAddress:  server.Addr(),
+			RedisDatabase: 2,
+			RedisUsername: "semver",
+			RedisPassword: "secret",
+		}),
+		Key: "some-version",
+	}
+	DeferCleanup(d.Client.Close)
+
+	return d, server
+}
+
+func redisCASSubject() casSubject {
+	d, server := newFakeRedisDriver()
+
+	return casSubject{
+		driver: d,
+		store:  func(v string) { server.DB(2).Set("some-version", v) },
+		current: func() string {
+			value, err := server.DB(2).Get("some-version")
+			Expect(err).NotTo(HaveOccurred())
+			return value
+		},
+		race: func(versions ...string) {
+			d.Client.AddHook(&concurrentWriteHook{server: server, writes: versions})
+		},
+	}
+}
@@ -6,11 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
+	"slices"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/blang/semver"
 	"github.com/concourse/semver-resource/version"
 )
@@ -18,6 +23,7 @@ import (
 type Servicer interface {
 	GetObject(context.Context, *s3.GetObjectInput, ...func(*s3.Options)) (*s3.GetObjectOutput, error)
 	PutObject(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	ListObjectVersions(context.Context, *s3.ListObjectVersionsInput, ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error)
 }
 
 type S3Driver struct {
@@ -31,45 +37,207 @@ type S3Driver struct {
 }
 
 func (driver *S3Driver) Bump(bump version.Bump) (semver.Version, error) {
-	var currentVersion semver.Version
+	// only write if nobody else has written since we read, otherwise re-read
+	// and re-apply the bump
+	return retryOnConflict(bump, driver.readVersion, driver.writeVersion, isPreconditionFailed)
+}
+
+func (driver *S3Driver) Set(newVersion semver.Version) error {
+	_, err := driver.Svc.PutObject(context.TODO(), driver.putObjectInput(newVersion))
+	return err
+}
 
-	resp, err := driver.Svc.GetObject(context.TODO(),
-		&s3.GetObjectInput{
-			Bucket: aws.String(driver.BucketName),
-			Key:    aws.String(driver.Key),
-		})
+func (driver *S3Driver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	var bucketNumber string
+	var versionID *string
+
+	resp, err := driver.Svc.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(driver.BucketName),
+		Key:    aws.String(driver.Key),
+	})
 	if err == nil {
 		bucketNumberPayload, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return semver.Version{}, err
+			return []semver.Version{}, err
 		}
 		defer resp.Body.Close()
 
-		payloadStr := strings.TrimSpace(string(bucketNumberPayload))
-		currentVersion, err = semver.Parse(payloadStr)
-		if err != nil {
-			return semver.Version{}, err
-		}
+		bucketNumber = string(bucketNumberPayload)
+		versionID = resp.VersionId
 	} else {
 		var noSuchKey *types.NoSuchKey
 		if errors.As(err, &noSuchKey) {
-			currentVersion = driver.InitialVersion
+			if cursor == nil {
+				return []semver.Version{driver.InitialVersion}, nil
+			} else {
+				return []semver.Version{}, nil
+			}
 		} else {
-			return semver.Version{}, err
+			return nil, err
+		}
+	}
+
+	// especially when manually fixing versions, extraneous newlines can
+	// be ended to the bucket file
+	bucketVersion, err := semver.Parse(strings.TrimSpace(bucketNumber))
+	if err != nil {
+		return nil, fmt.Errorf("parsing number in bucket: %s", err)
+	}
+
+	// Handle a "fly check-resource --from <cursor>" to bring back old versions,
+	// which is only possible when the bucket has versioning enabled
+	if cursor != nil && versionID != nil {
+		oldVersions, err := driver.getOldVersions(cursor, bucketVersion, *versionID)
+		if isAccessDenied(err) {
+			fmt.Fprintf(os.Stderr, "not permitted to read object versions, skipping version history: %s\n", err)
+			return []semver.Version{bucketVersion}, nil
+		}
+
+		return oldVersions, err
+	}
+
+	return []semver.Version{bucketVersion}, nil
+}
+
+// getOldVersions() goes back through the object's versions to find all
+// versions newer than the cursor, starting from the one Check just read.
+// The loop ends when we find a version older than the cursor or run out of
+// object versions.
+func (driver *S3Driver) getOldVersions(cursor *semver.Version, currentVersion semver.Version, currentVersionID string) ([]semver.Version, error) {
+	// Supplied cursor version is newer or equal to current, so we do not need to go back in history
+	if cursor.GTE(currentVersion) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	oldVersions := []semver.Version{currentVersion}
+	foundCurrent := false
+
+	params := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(driver.BucketName),
+		Prefix: aws.String(driver.Key),
+	}
+
+	for {
+		page, err := driver.Svc.ListObjectVersions(context.TODO(), params)
+		if err != nil {
+			return nil, fmt.Errorf("listing object versions: %w", err)
+		}
+
+		// versions of a key are listed newest first
+		for _, objectVersion := range page.Versions {
+			if aws.ToString(objectVersion.Key) != driver.Key {
+				continue
+			}
+
+			// skip anything written after the version we started from
+			if !foundCurrent {
+				foundCurrent = aws.ToString(objectVersion.VersionId) == currentVersionID
+				continue
+			}
+
+			previousVersion, err := driver.readVersionID(aws.ToString(objectVersion.VersionId))
+			if err != nil {
+				return nil, err
+			}
+
+			// If cursor is newer than previous version, we've found all versions between cursor and current
+			if cursor.GT(previousVersion) {
+				slices.Reverse(oldVersions)
+				return oldVersions, nil
+			}
+
+			// Cursor is older or equal to previous version, so include previous version and continue
+			oldVersions = append(oldVersions, previousVersion)
 		}
+
+		if !aws.ToBool(page.IsTruncated) {
+			break
+		}
+
+		params.KeyMarker = page.NextKeyMarker
+		params.VersionIdMarker = page.NextVersionIdMarker
 	}
 
-	newVersion := bump.Apply(currentVersion)
+	// We have reached the beginning of history, so return what we have
+	slices.Reverse(oldVersions)
+	return oldVersions, nil
+}
 
-	err = driver.Set(newVersion)
+func (driver *S3Driver) readVersionID(versionID string) (semver.Version, error) {
+	resp, err := driver.Svc.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket:    aws.String(driver.BucketName),
+		Key:       aws.String(driver.Key),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return semver.Version{}, err
+	}
+	defer resp.Body.Close()
+
+	bucketNumberPayload, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return semver.Version{}, err
 	}
 
-	return newVersion, nil
+	previousVersion, err := semver.Parse(strings.TrimSpace(string(bucketNumberPayload)))
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("parsing number in object version %s: %s", versionID, err)
+	}
+
+	return previousVersion, nil
 }
 
-func (driver *S3Driver) Set(newVersion semver.Version) error {
+// readVersion returns the current version along with the ETag of the object
+// it was read from. The ETag is nil when the object does not exist yet.
+func (driver *S3Driver) readVersion() (semver.Version, *string, error) {
+	resp, err := driver.Svc.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(driver.BucketName),
+		Key:    aws.String(driver.Key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return driver.InitialVersion, nil, nil
+		}
+
+		return semver.Version{}, nil, err
+	}
+	defer resp.Body.Close()
+
+	bucketNumberPayload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return semver.Version{}, nil, err
+	}
+
+	currentVersion, err := semver.Parse(strings.TrimSpace(string(bucketNumberPayload)))
+	if err != nil {
+		return semver.Version{}, nil, err
+	}
+
+	return currentVersion, resp.ETag, nil
+}
+
+// writeVersion puts the version only if the object still has the given ETag,
+// or, when etag is nil, only if the object does not exist yet. Providers that
+// do not implement conditional writes get an unconditional put instead.
+func (driver *S3Driver) writeVersion(newVersion semver.Version, etag *string) error {
+	params := driver.putObjectInput(newVersion)
+	if etag != nil {
+		params.IfMatch = etag
+	} else {
+		params.IfNoneMatch = aws.String("*")
+	}
+
+	_, err := driver.Svc.PutObject(context.TODO(), params)
+	if isNotImplemented(err) {
+		fmt.Fprintf(os.Stderr, "conditional writes are not supported, writing unconditionally: %s\n", err)
+		_, err = driver.Svc.PutObject(context.TODO(), driver.putObjectInput(newVersion))
+	}
+
+	return err
+}
+
+func (driver *S3Driver) putObjectInput(newVersion semver.Version) *s3.PutObjectInput {
 	params := &s3.PutObjectInput{
 		Bucket:      aws.String(driver.BucketName),
 		Key:         aws.String(driver.Key),
@@ -85,44 +253,41 @@ func (driver *S3Driver) Set(newVersion semver.Version) error {
 		params.ChecksumAlgorithm = driver.ChecksumAlgorithm
 	}
 
-	_, err := driver.Svc.PutObject(context.TODO(), params)
-	return err
+	return params
 }
 
-func (driver *S3Driver) Check(cursor *semver.Version) ([]semver.Version, error) {
-	var bucketNumber string
-
-	resp, err := driver.Svc.GetObject(context.TODO(), &s3.GetObjectInput{
-		Bucket: aws.String(driver.BucketName),
-		Key:    aws.String(driver.Key),
-	})
-	if err == nil {
-		bucketNumberPayload, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return []semver.Version{}, err
-		}
-		defer resp.Body.Close()
+// isAccessDenied reports whether the request was refused for lack of
+// permissions, e.g. s3:ListBucketVersions.
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDenied"
+}
 
-		bucketNumber = string(bucketNumberPayload)
-	} else {
-		var noSuchKey *types.NoSuchKey
-		if errors.As(err, &noSuchKey) {
-			if cursor == nil {
-				return []semver.Version{driver.InitialVersion}, nil
-			} else {
-				return []semver.Version{}, nil
-			}
-		} else {
-			return nil, err
-		}
+// isNotImplemented reports whether the provider rejected the request as
+// unsupported, as older S3 compatible stores do with conditional writes.
+func isNotImplemented(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NotImplemented" {
+		return true
 	}
 
-	// especially when manually fixing versions, extraneous newlines can
-	// be ended to the bucket file
-	bucketVersion, err := semver.Parse(strings.TrimSpace(bucketNumber))
-	if err != nil {
-		return nil, fmt.Errorf("parsing number in bucket: %s", err)
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotImplemented
+}
+
+// isPreconditionFailed reports whether a conditional write was rejected
+// because the object changed underneath us. S3 answers 409 instead of 412
+// when a competing conditional write is still in flight.
+func isPreconditionFailed(err error) bool {
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
 	}
 
-	return []semver.Version{bucketVersion}, nil
+	switch respErr.HTTPStatusCode() {
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return true
+	default:
+		return false
+	}
 }
@@ -2,11 +2,21 @@ package driver_test
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/blang/semver"
 	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/version"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -31,6 +41,189 @@ var _ = Describe("S3 Driver", func() {
 			Expect(s.params.ServerSideEncryption).To(BeEmpty())
 		})
 	})
+
+	Context("bumping", func() {
+		var s *conditionalService
+		var d *driver.S3Driver
+
+		BeforeEach(func() {
+			s = &conditionalService{}
+			d = &driver.S3Driver{
+				InitialVersion: semver.Version{Major: 1},
+				Svc:            s,
+				BucketName:     "some-bucket",
+				Key:            "some-key",
+			}
+		})
+
+		Context("when the object exists", func() {
+			BeforeEach(func() {
+				s.store("1.2.3")
+			})
+
+			It("writes the bumped version only if the object is unchanged", func() {
+				newVersion, err := d.Bump(version.PatchBump{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(newVersion.String()).To(Equal("1.2.4"))
+				Expect(s.body).To(Equal("1.2.4"))
+
+				Expect(s.puts).To(HaveLen(1))
+				Expect(s.puts[0].IfMatch).To(Equal(aws.String(`"1"`)))
+				Expect(s.puts[0].IfNoneMatch).To(BeNil())
+			})
+		})
+
+		Context("when the object does not exist", func() {
+			It("bumps the initial version only if the object is still absent", func() {
+				newVersion, err := d.Bump(version.MinorBump{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(newVersion.String()).To(Equal("1.1.0"))
+				Expect(s.body).To(Equal("1.1.0"))
+
+				Expect(s.puts).To(HaveLen(1))
+				Expect(s.puts[0].IfMatch).To(BeNil())
+				Expect(s.puts[0].IfNoneMatch).To(Equal(aws.String("*")))
+			})
+		})
+
+		It("writes unconditionally when the provider does not support conditional writes", func() {
+			s.noConditions = true
+			s.store("1.2.3")
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(s.body).To(Equal("1.2.4"))
+
+			Expect(s.puts).To(HaveLen(2))
+			Expect(s.puts[1].IfMatch).To(BeNil())
+			Expect(s.puts[1].IfNoneMatch).To(BeNil())
+		})
+
+		It("writes unconditionally when setting", func() {
+			s.store("5.0.0")
+
+			err := d.Set(semver.Version{Major: 4})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.body).To(Equal("4.0.0"))
+
+			Expect(s.puts[0].IfMatch).To(BeNil())
+			Expect(s.puts[0].IfNoneMatch).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("S3 Driver history", func() {
+	var s *versionedService
+	var d *driver.S3Driver
+
+	BeforeEach(func() {
+		s = &versionedService{}
+		d = &driver.S3Driver{
+			Svc:        s,
+			BucketName: "some-bucket",
+			Key:        "some-key",
+		}
+	})
+
+	Context("when the bucket has versioning enabled", func() {
+		BeforeEach(func() {
+			s.versioned = true
+			s.put("some-key-suffix", "9.9.9")
+			for _, v := range []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0", "2.0.0"} {
+				s.put("some-key", v)
+			}
+		})
+
+		It("returns only the current version without a cursor", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+			Expect(s.listCalls).To(BeZero())
+		})
+
+		It("returns every version from the cursor onwards, oldest first", func() {
+			cursor := semver.MustParse("1.1.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.1.0"),
+				semver.MustParse("1.2.0"),
+				semver.MustParse("1.3.0"),
+				semver.MustParse("2.0.0"),
+			}))
+		})
+
+		It("follows paginated listings back to the beginning of history", func() {
+			s.pageSize = 2
+
+			cursor := semver.MustParse("0.1.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(HaveLen(5))
+			Expect(versions[0]).To(Equal(semver.MustParse("1.0.0")))
+			Expect(versions[4]).To(Equal(semver.MustParse("2.0.0")))
+			Expect(s.listCalls).To(BeNumerically(">", 1))
+		})
+
+		It("returns only the current version when the cursor is not older", func() {
+			cursor := semver.MustParse("2.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+			Expect(s.listCalls).To(BeZero())
+		})
+
+		It("returns an error when an old version cannot be parsed", func() {
+			s.put("some-key", "garbage")
+			s.put("some-key", "3.0.0")
+
+			cursor := semver.MustParse("1.0.0")
+			_, err := d.Check(&cursor)
+			Expect(err).To(MatchError(ContainSubstring("parsing number in object version")))
+		})
+
+		It("returns only the current version when listing versions is denied", func() {
+			s.listErr = &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}
+
+			cursor := semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+		})
+
+		It("returns only the current version when reading old versions is denied", func() {
+			s.getVersionErr = &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}
+
+			cursor := semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+		})
+
+		It("returns other errors when listing versions", func() {
+			s.listErr = &smithy.GenericAPIError{Code: "InternalError", Message: "We encountered an internal error"}
+
+			cursor := semver.MustParse("1.0.0")
+			_, err := d.Check(&cursor)
+			Expect(err).To(MatchError(ContainSubstring("listing object versions")))
+		})
+	})
+
+	Context("when the bucket does not have versioning enabled", func() {
+		BeforeEach(func() {
+			s.put("some-key", "1.0.0")
+			s.put("some-key", "2.0.0")
+		})
+
+		It("returns only the current version", func() {
+			cursor := semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+			Expect(s.listCalls).To(BeZero())
+		})
+	})
 })
 
 type service struct {
@@ -45,3 +238,213 @@ func (s *service) PutObject(ctx context.Context, p *s3.PutObjectInput, opts ...f
 	s.params = p
 	return nil, nil
 }
+
+func (*service) ListObjectVersions(ctx context.Context, p *s3.ListObjectVersionsInput, opts ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return &s3.ListObjectVersionsOutput{}, nil
+}
+
+// conditionalService is an in-memory object honouring If-Match and
+// If-None-Match the way S3 does. Each entry in concurrentWrites is stored
+// right before the next put, simulating another pipeline winning the race.
+type conditionalService struct {
+	body   string
+	exists bool
+	etag   int
+
+	concurrentWrites []string
+	noConditions     bool
+
+	puts []*s3.PutObjectInput
+}
+
+func (s *conditionalService) store(body string) {
+	s.body = body
+	s.exists = true
+	s.etag++
+}
+
+func (s *conditionalService) currentETag() string {
+	return fmt.Sprintf(`"%d"`, s.etag)
+}
+
+func (s *conditionalService) GetObject(ctx context.Context, p *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if !s.exists {
+		return nil, &types.NoSuchKey{}
+	}
+
+	return &s3.GetObjectOutput{
+		Body: io.NopCloser(strings.NewReader(s.body)),
+		ETag: aws.String(s.currentETag()),
+	}, nil
+}
+
+func (s *conditionalService) PutObject(ctx context.Context, p *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	s.puts = append(s.puts, p)
+
+	if len(s.concurrentWrites) > 0 {
+		s.store(s.concurrentWrites[0])
+		s.concurrentWrites = s.concurrentWrites[1:]
+	}
+
+	if s.noConditions && (p.IfMatch != nil || p.IfNoneMatch != nil) {
+		return nil, notImplemented()
+	}
+
+	if p.IfMatch != nil && (!s.exists || *p.IfMatch != s.currentETag()) {
+		return nil, preconditionFailed()
+	}
+
+	if p.IfNoneMatch != nil && s.exists {
+		return nil, preconditionFailed()
+	}
+
+	body, err := io.ReadAll(p.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	s.store(string(body))
+
+	return &s3.PutObjectOutput{ETag: aws.String(s.currentETag())}, nil
+}
+
+func (s *conditionalService) ListObjectVersions(ctx context.Context, p *s3.ListObjectVersionsInput, opts ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	return &s3.ListObjectVersionsOutput{}, nil
+}
+
+// versionedService keeps every write like a bucket with versioning enabled.
+// Listings are returned newest first and split into pages of pageSize.
+type versionedService struct {
+	versioned bool
+	pageSize  int
+
+	objects   []objectVersion
+	listCalls int
+
+	listErr       error
+	getVersionErr error
+}
+
+type objectVersion struct {
+	key  string
+	id   string
+	body string
+}
+
+func (s *versionedService) put(key, body string) {
+	id := "null"
+	if s.versioned {
+		id = fmt.Sprintf("v%d", len(s.objects))
+	} else {
+		s.objects = slices.DeleteFunc(s.objects, func(o objectVersion) bool { return o.key == key })
+	}
+
+	s.objects = append([]objectVersion{{key: key, id: id, body: body}}, s.objects...)
+}
+
+func (s *versionedService) GetObject(ctx context.Context, p *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if p.VersionId != nil && s.getVersionErr != nil {
+		return nil, s.getVersionErr
+	}
+
+	for _, o := range s.objects {
+		if o.key != aws.ToString(p.Key) || (p.VersionId != nil && o.id != *p.VersionId) {
+			continue
+		}
+
+		out := &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(o.body))}
+		if s.versioned {
+			out.VersionId = aws.String(o.id)
+		}
+		return out, nil
+	}
+
+	return nil, &types.NoSuchKey{}
+}
+
+func (s *versionedService) PutObject(ctx context.Context, p *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(p.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	s.put(aws.ToString(p.Key), string(body))
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (s *versionedService) ListObjectVersions(ctx context.Context, p *s3.ListObjectVersionsInput, opts ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	s.listCalls++
+
+	if s.listErr != nil {
+		return nil, s.listErr
+	}
+
+	start := 0
+	if p.VersionIdMarker != nil {
+		start = slices.IndexFunc(s.objects, func(o objectVersion) bool { return o.id == *p.VersionIdMarker }) + 1
+	}
+
+	end := len(s.objects)
+	if s.pageSize > 0 {
+		end = min(start+s.pageSize, end)
+	}
+
+	out := &s3.ListObjectVersionsOutput{IsTruncated: aws.Bool(end < len(s.objects))}
+	for i, o := range s.objects[start:end] {
+		if !strings.HasPrefix(o.key, aws.ToString(p.Prefix)) {
+			continue
+		}
+
+		out.Versions = append(out.Versions, types.ObjectVersion{
+			Key:       aws.String(o.key),
+			VersionId: aws.String(o.id),
+			IsLatest:  aws.Bool(i+start == slices.IndexFunc(s.objects, func(c objectVersion) bool { return c.key == o.key })),
+		})
+	}
+
+	if aws.ToBool(out.IsTruncated) {
+		last := s.objects[end-1]
+		out.NextKeyMarker = aws.String(last.key)
+		out.NextVersionIdMarker = aws.String(last.id)
+	}
+
+	return out, nil
+}
+
+func preconditionFailed() error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{
+				Response: &http.Response{StatusCode: http.StatusPreconditionFailed},
+			},
+			Err: fmt.Errorf("PreconditionFailed"),
+		},
+	}
+}
+
+func notImplemented() error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{
+				Response: &http.Response{StatusCode: http.StatusNotImplemented},
+			},
+			Err: fmt.Errorf("NotImplemented"),
+		},
+	}
+}
+
+func s3CASSubject() casSubject {
+	s := &conditionalService{}
+
+	return casSubject{
+		driver: &driver.S3Driver{
+			InitialVersion: semver.Version{Major: 1},
+			Svc:            s,
+			BucketName:     "some-bucket",
+			Key:            "some-key",
+		},
+		store:   s.store,
+		current: func() string { return s.body },
+		race:    func(versions ...string) { s.concurrentWrites = versions },
+	}
+}
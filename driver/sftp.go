@@ -0,0 +1,353 @@
+package driver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+)
+
+// SFTPDriver stores the version in a file on an SSH host. Writers serialize on
+// a sibling ".lock" file that is created exclusively. The version file is
+// replaced by renaming a complete copy over it where the host supports that,
+// and rewritten in place otherwise.
+type SFTPDriver struct {
+	InitialVersion semver.Version
+
+	Address      string
+	ClientConfig *ssh.ClientConfig
+	Path         string
+
+	// LockTimeout is how long to wait for another writer to remove the lock
+	// file before giving up.
+	LockTimeout time.Duration
+
+	// StaleLockAge is how old a lock file has to be to be considered left
+	// behind by an interrupted writer and taken over. Zero never takes over.
+	StaleLockAge time.Duration
+}
+
+const sftpLockPollInterval = 500 * time.Millisecond
+
+// NewSSHClientConfig authenticates with the private key or password from the
+// source, and only accepts the host key given in the source.
+func NewSSHClientConfig(source models.Source) (*ssh.ClientConfig, error) {
+	if source.Username == "" {
+		return nil, fmt.Errorf("must specify username for the sftp driver")
+	}
+
+	if source.HostKey == "" {
+		return nil, fmt.Errorf("must specify host_key for the sftp driver")
+	}
+
+	hostKey, err := parseHostKey(source.HostKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing host_key: %w", err)
+	}
+
+	var auth []ssh.AuthMethod
+
+	if source.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(source.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("parsing private_key: %w", err)
+		}
+
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+
+	if source.Password != "" {
+		auth = append(auth, ssh.Password(source.Password))
+	}
+
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("must specify private_key or password for the sftp driver")
+	}
+
+	return &ssh.ClientConfig{
+		User:            source.Username,
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         30 * time.Second,
+	}, nil
+}
+
+// parseHostKey accepts a public key as found in authorized_keys, or a line of
+// known_hosts as printed by ssh-keyscan.
+func parseHostKey(s string) (ssh.PublicKey, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(s))
+	if err == nil {
+		return key, nil
+	}
+
+	_, _, key, _, _, err = ssh.ParseKnownHosts([]byte(s))
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (driver *SFTPDriver) Bump(bump version.Bump) (semver.Version, error) {
+	var newVersion semver.Version
+
+	err := driver.withClient(func(client *sftp.Client) error {
+		unlock, err := driver.lock(client)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		currentVersion, exists, err := driver.readVersion(client)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			currentVersion = driver.InitialVersion
+		}
+
+		newVersion, err = version.Apply(bump, currentVersion)
+		if err != nil {
+			return err
+		}
+
+		return driver.writeVersion(client, newVersion)
+	})
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	return newVersion, nil
+}
+
+func (driver *SFTPDriver) Set(newVersion semver.Version) error {
+	return driver.withClient(func(client *sftp.Client) error {
+		unlock, err := driver.lock(client)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		return driver.writeVersion(client, newVersion)
+	})
+}
+
+func (driver *SFTPDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	var versions []semver.Version
+
+	err := driver.withClient(func(client *sftp.Client) error {
+		currentVersion, exists, err := driver.readVersion(client)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			if cursor == nil {
+				versions = []semver.Version{driver.InitialVersion}
+			} else {
+				versions = []semver.Version{}
+			}
+			return nil
+		}
+
+		// the file keeps no history, so only the current version can be reported
+		versions = []semver.Version{currentVersion}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (driver *SFTPDriver) withClient(fn func(*sftp.Client) error) error {
+	address := driver.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "22")
+	}
+
+	conn, err := ssh.Dial("tcp", address, driver.ClientConfig)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return fmt.Errorf("starting sftp session: %w", err)
+	}
+	defer client.Close()
+
+	return fn(client)
+}
+
+// lock creates the lock file, waiting for another writer to remove it first
+// if it already exists. SFTP does not tell an existing file apart from other
+// failures to create one exclusively, so the lock file is looked up after a
+// failed attempt. The lock file holds a random token so that only its owner
+// removes it.
+//
+// A lock file older than StaleLockAge is removed and the lock taken over. Its
+// age is told by the host's clock, and two writers taking over the same stale
+// lock at once may both get it, so StaleLockAge has to be well above how long
+// any write holds the lock.
+func (driver *SFTPDriver) lock(client *sftp.Client) (func(), error) {
+	lockPath := driver.Path + ".lock"
+	deadline := time.Now().Add(driver.LockTimeout)
+
+	token, err := lockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		lockFile, err := client.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+		if err == nil {
+			_, err = lockFile.Write([]byte(token))
+			lockFile.Close()
+			if err != nil {
+				client.Remove(lockPath)
+				return nil, fmt.Errorf("writing lock file %s: %w", lockPath, err)
+			}
+
+			return func() { driver.unlock(client, lockPath, token) }, nil
+		}
+
+		info, statErr := client.Stat(lockPath)
+		if statErr != nil {
+			return nil, fmt.Errorf("creating lock file %s: %w", lockPath, err)
+		}
+
+		if driver.StaleLockAge > 0 && time.Since(info.ModTime()) > driver.StaleLockAge {
+			fmt.Fprintf(os.Stderr, "taking over stale lock file %s from %s\n", lockPath, info.ModTime().Format(time.RFC3339))
+
+			err = client.Remove(lockPath)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("removing stale lock file %s: %w", lockPath, err)
+			}
+
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock file %s to be removed", lockPath)
+		}
+
+		time.Sleep(sftpLockPollInterval)
+	}
+}
+
+// unlock removes the lock file, unless it was taken over by another writer in
+// the meantime.
+func (driver *SFTPDriver) unlock(client *sftp.Client, lockPath string, token string) {
+	lockFile, err := client.Open(lockPath)
+	if err != nil {
+		return
+	}
+
+	contents, err := io.ReadAll(lockFile)
+	lockFile.Close()
+	if err != nil || string(contents) != token {
+		return
+	}
+
+	client.Remove(lockPath)
+}
+
+func (driver *SFTPDriver) readVersion(client *sftp.Client) (semver.Version, bool, error) {
+	file, err := client.Open(driver.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return semver.Version{}, false, nil
+	}
+
+	if err != nil {
+		return semver.Version{}, false, err
+	}
+	defer file.Close()
+
+	contents, err := io.ReadAll(file)
+	if err != nil {
+		return semver.Version{}, false, err
+	}
+
+	currentVersion, err := semver.Parse(strings.TrimSpace(string(contents)))
+	if err != nil {
+		return semver.Version{}, false, fmt.Errorf("parsing number in %s: %s", driver.Path, err)
+	}
+
+	return currentVersion, true, nil
+}
+
+// writeVersion writes the version to a temporary file next to the version
+// file and renames it into place, so readers never see a partial write.
+// Renaming over an existing file needs the posix-rename@openssh.com
+// extension; hosts without it get the version file rewritten in place.
+func (driver *SFTPDriver) writeVersion(client *sftp.Client, newVersion semver.Version) error {
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); !ok {
+		return driver.overwriteVersion(client, newVersion)
+	}
+
+	suffix := make([]byte, 8)
+	_, err := rand.Read(suffix)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path.Join(path.Dir(driver.Path), "."+path.Base(driver.Path)+"."+hex.EncodeToString(suffix))
+
+	tmp, err := client.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return err
+	}
+	defer client.Remove(tmpPath)
+
+	_, err = tmp.Write([]byte(newVersion.String() + "\n"))
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = client.Chmod(tmpPath, 0644)
+	if err != nil {
+		return err
+	}
+
+	return client.PosixRename(tmpPath, driver.Path)
+}
+
+// overwriteVersion truncates the version file and writes the version to it,
+// so a reader may see an empty or partial file in the meantime.
+func (driver *SFTPDriver) overwriteVersion(client *sftp.Client, newVersion semver.Version) error {
+	file, err := client.OpenFile(driver.Path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write([]byte(newVersion.String() + "\n"))
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,413 @@
+package driver_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SFTP Driver", func() {
+	var (
+		root       string
+		address    string
+		hostKey    ssh.PublicKey
+		privateKey string
+		source     models.Source
+		d          *driver.SFTPDriver
+	)
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		address, hostKey, privateKey = startSFTPServer()
+
+		source = models.Source{
+			Host:       address,
+			Username:   "semver",
+			PrivateKey: privateKey,
+			HostKey:    string(ssh.MarshalAuthorizedKey(hostKey)),
+		}
+
+		clientConfig, err := driver.NewSSHClientConfig(source)
+		Expect(err).NotTo(HaveOccurred())
+
+		d = &driver.SFTPDriver{
+			InitialVersion: semver.Version{Major: 1},
+			Address:        address,
+			ClientConfig:   clientConfig,
+			Path:           filepath.Join(root, "version"),
+			LockTimeout:    2 * time.Second,
+		}
+	})
+
+	current := func() string {
+		contents, err := os.ReadFile(d.Path)
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	Describe("Check", func() {
+		It("returns the initial version when the file does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns no versions for a cursor when the file does not exist", func() {
+			cursor := semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(BeEmpty())
+		})
+
+		It("returns the version in the file", func() {
+			Expect(os.WriteFile(d.Path, []byte("2.3.4\n"), 0644)).To(Succeed())
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("returns an error when the file does not contain a version", func() {
+			Expect(os.WriteFile(d.Path, []byte("bogus"), 0644)).To(Succeed())
+
+			_, err := d.Check(nil)
+			Expect(err).To(MatchError(ContainSubstring("parsing number in " + d.Path)))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("creates the file when it does not exist", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(current()).To(Equal("1.1.0\n"))
+		})
+
+		It("leaves only the version file behind", func() {
+			Expect(os.WriteFile(d.Path, []byte("1.2.3"), 0644)).To(Succeed())
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(current()).To(Equal("1.2.4\n"))
+
+			entries, err := os.ReadDir(root)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(Equal("version"))
+		})
+
+		It("waits for the lock file to be removed", func() {
+			Expect(os.WriteFile(d.Path, []byte("1.2.3"), 0644)).To(Succeed())
+			Expect(os.WriteFile(d.Path+".lock", nil, 0644)).To(Succeed())
+
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(time.Second)
+				Expect(os.WriteFile(d.Path, []byte("1.5.0"), 0644)).To(Succeed())
+				Expect(os.Remove(d.Path + ".lock")).To(Succeed())
+			}()
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.5.1"))
+		})
+
+		It("takes over a stale lock file", func() {
+			d.StaleLockAge = time.Hour
+			Expect(os.WriteFile(d.Path, []byte("1.2.3"), 0644)).To(Succeed())
+			Expect(os.WriteFile(d.Path+".lock", nil, 0644)).To(Succeed())
+
+			stale := time.Now().Add(-2 * time.Hour)
+			Expect(os.Chtimes(d.Path+".lock", stale, stale)).To(Succeed())
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(d.Path + ".lock").NotTo(BeAnExistingFile())
+		})
+
+		It("leaves a lock file that another writer took over", func() {
+			Expect(os.WriteFile(d.Path, []byte("1.2.3"), 0644)).To(Succeed())
+
+			// stands in for a writer taking the lock over while the bump
+			// holds it, right before the bump reads the version
+			sftpBeforeOpen = func(name string) {
+				if name == d.Path {
+					Expect(os.WriteFile(d.Path+".lock", []byte("someone-else"), 0644)).To(Succeed())
+				}
+			}
+			DeferCleanup(func() { sftpBeforeOpen = nil })
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(os.ReadFile(d.Path + ".lock")).To(Equal([]byte("someone-else")))
+		})
+
+		It("rewrites the file in place when the host cannot rename over it", func() {
+			Expect(sftp.SetSFTPExtensions("hardlink@openssh.com", "statvfs@openssh.com")).To(Succeed())
+			DeferCleanup(sftp.SetSFTPExtensions, "hardlink@openssh.com", "posix-rename@openssh.com", "statvfs@openssh.com")
+
+			Expect(os.WriteFile(d.Path, []byte("1.2.3"), 0644)).To(Succeed())
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(current()).To(Equal("1.2.4\n"))
+
+			entries, err := os.ReadDir(root)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+
+		It("gives up when the lock file is not removed in time", func() {
+			Expect(os.WriteFile(d.Path+".lock", nil, 0644)).To(Succeed())
+
+			_, err := d.Bump(version.PatchBump{})
+			Expect(err).To(MatchError(ContainSubstring("timed out waiting for lock file")))
+		})
+
+		It("serializes concurrent bumps", func() {
+			var wg sync.WaitGroup
+			versions := make(chan string, 5)
+
+			for range 5 {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					newVersion, err := d.Bump(version.PatchBump{})
+					Expect(err).NotTo(HaveOccurred())
+					versions <- newVersion.String()
+				}()
+			}
+
+			wg.Wait()
+			close(versions)
+
+			var bumped []string
+			for v := range versions {
+				bumped = append(bumped, v)
+			}
+
+			Expect(bumped).To(ConsistOf("1.0.1", "1.0.2", "1.0.3", "1.0.4", "1.0.5"))
+			Expect(current()).To(Equal("1.0.5\n"))
+		})
+	})
+
+	Describe("Set", func() {
+		It("replaces the file", func() {
+			Expect(os.WriteFile(d.Path, []byte("1.2.3"), 0644)).To(Succeed())
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current()).To(Equal("5.0.0\n"))
+		})
+	})
+
+	Describe("NewSSHClientConfig", func() {
+		It("authenticates with a password", func() {
+			source.PrivateKey = ""
+			source.Password = "secret"
+
+			clientConfig, err := driver.NewSSHClientConfig(source)
+			Expect(err).NotTo(HaveOccurred())
+			d.ClientConfig = clientConfig
+
+			_, err = d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("accepts the host key as a known_hosts line", func() {
+			source.HostKey = "[127.0.0.1]:2222 " + string(ssh.MarshalAuthorizedKey(hostKey))
+
+			clientConfig, err := driver.NewSSHClientConfig(source)
+			Expect(err).NotTo(HaveOccurred())
+			d.ClientConfig = clientConfig
+
+			_, err = d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("refuses a host presenting a different host key", func() {
+			_, otherHostKey, _ := startSFTPServer()
+			source.HostKey = string(ssh.MarshalAuthorizedKey(otherHostKey))
+
+			clientConfig, err := driver.NewSSHClientConfig(source)
+			Expect(err).NotTo(HaveOccurred())
+			d.ClientConfig = clientConfig
+
+			_, err = d.Check(nil)
+			Expect(err).To(MatchError(ContainSubstring("host key mismatch")))
+		})
+
+		It("requires a host key", func() {
+			source.HostKey = ""
+
+			_, err := driver.NewSSHClientConfig(source)
+			Expect(err).To(MatchError("must specify host_key for the sftp driver"))
+		})
+
+		It("requires a private key or password", func() {
+			source.PrivateKey = ""
+
+			_, err := driver.NewSSHClientConfig(source)
+			Expect(err).To(MatchError("must specify private_key or password for the sftp driver"))
+		})
+	})
+})
+
+// startSFTPServer serves the local filesystem over SFTP to the user "semver",
+// who can log in with the returned private key or the password "secret".
+func startSFTPServer() (string, ssh.PublicKey, string) {
+	_, hostPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivateKey)
+	Expect(err).NotTo(HaveOccurred())
+
+	_, userPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	userSigner, err := ssh.NewSignerFromKey(userPrivateKey)
+	Expect(err).NotTo(HaveOccurred())
+
+	userPEM, err := ssh.MarshalPrivateKey(userPrivateKey, "")
+	Expect(err).NotTo(HaveOccurred())
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "semver" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("wrong password for %s", conn.User())
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "semver" && bytes.Equal(key.Marshal(), userSigner.PublicKey().Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key for %s", conn.User())
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(listener.Close)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveSFTP(conn, config)
+		}
+	}()
+
+	return listener.Addr().String(), hostSigner.PublicKey(), string(pem.EncodeToMemory(userPEM))
+}
+
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer channel.Close()
+
+			for req := range requests {
+				// the payload of a subsystem request is the length-prefixed name
+				isSFTP := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(isSFTP, nil)
+				if !isSFTP {
+					continue
+				}
+
+				server, err := sftp.NewServer(&openHookedChannel{Channel: channel})
+				if err != nil {
+					return
+				}
+
+				server.Serve()
+				return
+			}
+		}()
+	}
+}
+
+// sftpBeforeOpen, if set, is called by the server with the path of every file
+// opened, before opening it.
+var sftpBeforeOpen func(path string)
+
+// openHookedChannel reads the client's SFTP packets one by one, calling
+// sftpBeforeOpen for every SSH_FXP_OPEN before handing it to the server.
+type openHookedChannel struct {
+	ssh.Channel
+
+	pending []byte
+}
+
+func (c *openHookedChannel) Read(p []byte) (int, error) {
+	if len(c.pending) == 0 {
+		header := make([]byte, 4)
+		_, err := io.ReadFull(c.Channel, header)
+		if err != nil {
+			return 0, err
+		}
+
+		packet := make([]byte, binary.BigEndian.Uint32(header))
+		_, err = io.ReadFull(c.Channel, packet)
+		if err != nil {
+			return 0, err
+		}
+
+		// type, request id, then the length-prefixed path
+		const sshFxpOpen = 3
+		if packet[0] == sshFxpOpen && sftpBeforeOpen != nil {
+			pathLen := binary.BigEndian.Uint32(packet[5:9])
+			sftpBeforeOpen(string(packet[9 : 9+pathLen]))
+		}
+
+		c.pending = append(header, packet...)
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,405 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+)
+
+// SQLDialect holds the statements that differ between the supported
+// databases.
+type SQLDialect struct {
+	// DriverName is the database/sql driver to open the database with.
+	DriverName string
+
+	// Placeholder returns the bind parameter for the nth (1-based) argument.
+	Placeholder func(n int) string
+
+	// ForUpdate is appended to the SELECT made by Bump to lock the row until
+	// the transaction ends.
+	ForUpdate string
+
+	// InsertIgnore inserts a row of the versions table unless its name
+	// already exists; %s is the table name.
+	InsertIgnore string
+
+	// HistoryID is the column definition of the history table's
+	// auto-incrementing primary key.
+	HistoryID string
+
+	// CreateHistoryIndex creates the index the history of a name is looked
+	// up with; %[1]s is the table name.
+	CreateHistoryIndex string
+
+	// IsExisting reports whether creating a table or the index failed because
+	// it exists: because the database cannot create an index only if it does
+	// not, or because another writer created it at the same time.
+	IsExisting func(error) bool
+
+	// IsUndefinedTable reports whether a query failed because a table does
+	// not exist.
+	IsUndefinedTable func(error) bool
+}
+
+var SQLDialects = map[string]SQLDialect{
+	"postgres": {
+		DriverName:   "pgx",
+		Placeholder:  func(n int) string { return fmt.Sprintf("$%d", n) },
+		ForUpdate:    " FOR UPDATE",
+		InsertIgnore: "INSERT INTO %s (name) VALUES ($1) ON CONFLICT (name) DO NOTHING",
+		HistoryID:    "id BIGSERIAL PRIMARY KEY",
+
+		CreateHistoryIndex: "CREATE INDEX IF NOT EXISTS %[1]s_name ON %[1]s (name, id)",
+		IsExisting: func(err error) bool {
+			// duplicate_table, or unique_violation in the catalogs when
+			// another CREATE ... IF NOT EXISTS got there first
+			var pgErr *pgconn.PgError
+			return errors.As(err, &pgErr) && (pgErr.Code == "42P07" || pgErr.Code == "23505")
+		},
+		IsUndefinedTable: func(err error) bool {
+			var pgErr *pgconn.PgError
+			return errors.As(err, &pgErr) && pgErr.Code == "42P01"
+		},
+	},
+	"mysql": {
+		DriverName:   "mysql",
+		Placeholder:  func(int) string { return "?" },
+		ForUpdate:    " FOR UPDATE",
+		InsertIgnore: "INSERT IGNORE INTO %s (name) VALUES (?)",
+		HistoryID:    "id BIGINT AUTO_INCREMENT PRIMARY KEY",
+
+		CreateHistoryIndex: "CREATE INDEX %[1]s_name ON %[1]s (name, id)",
+		IsExisting: func(err error) bool {
+			// ER_TABLE_EXISTS_ERROR, ER_DUP_KEYNAME
+			var mysqlErr *mysql.MySQLError
+			return errors.As(err, &mysqlErr) && (mysqlErr.Number == 1050 || mysqlErr.Number == 1061)
+		},
+		IsUndefinedTable: func(err error) bool {
+			// ER_NO_SUCH_TABLE
+			var mysqlErr *mysql.MySQLError
+			return errors.As(err, &mysqlErr) && mysqlErr.Number == 1146
+		},
+	},
+	// SQLite has no row locks; transactions are opened with BEGIN IMMEDIATE
+	// instead (see OpenSQLDatabase), which locks the whole database.
+	"sqlite": {
+		DriverName:   "sqlite",
+		Placeholder:  func(int) string { return "?" },
+		InsertIgnore: "INSERT INTO %s (name) VALUES (?) ON CONFLICT (name) DO NOTHING",
+		HistoryID:    "id INTEGER PRIMARY KEY AUTOINCREMENT",
+
+		CreateHistoryIndex: "CREATE INDEX IF NOT EXISTS %[1]s_name ON %[1]s (name, id)",
+		IsExisting:         func(error) bool { return false },
+		IsUndefinedTable: func(err error) bool {
+			// the driver reports it with the generic SQLITE_ERROR code
+			return err != nil && strings.Contains(err.Error(), "no such table")
+		},
+	},
+}
+
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLDriver stores versions in a table keyed by name, and appends every
+// version written to a history table.
+type SQLDriver struct {
+	InitialVersion semver.Version
+
+	DB            *sql.DB
+	Dialect       SQLDialect
+	Name          string
+	VersionsTable string
+	HistoryTable  string
+}
+
+func NewSQLDriver(source models.Source, initialVersion semver.Version) (*SQLDriver, error) {
+	dialect, found := SQLDialects[source.DatabaseType]
+	if !found {
+		return nil, fmt.Errorf("unknown database_type %q. Must be one of: %q", source.DatabaseType, slices.Sorted(maps.Keys(SQLDialects)))
+	}
+
+	if source.DatabaseURL == "" {
+		return nil, fmt.Errorf("must specify database_url for the sql driver")
+	}
+
+	if source.Key == "" {
+		return nil, fmt.Errorf("must specify key for the sql driver")
+	}
+
+	versionsTable := source.VersionsTable
+	if versionsTable == "" {
+		versionsTable = "semver_versions"
+	}
+
+	historyTable := source.HistoryTable
+	if historyTable == "" {
+		historyTable = "semver_history"
+	}
+
+	for _, table := range []string{versionsTable, historyTable} {
+		if !sqlIdentifier.MatchString(table) {
+			return nil, fmt.Errorf("invalid table name %q", table)
+		}
+	}
+
+	db, err := OpenSQLDatabase(dialect, source.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLDriver{
+		InitialVersion: initialVersion,
+
+		DB:            db,
+		Dialect:       dialect,
+		Name:          source.Key,
+		VersionsTable: versionsTable,
+		HistoryTable:  historyTable,
+	}, nil
+}
+
+// OpenSQLDatabase opens the database. SQLite transactions are made to take the
+// write lock as soon as they begin, as that is what serializes bumps there.
+func OpenSQLDatabase(dialect SQLDialect, url string) (*sql.DB, error) {
+	if dialect.DriverName == "sqlite" {
+		separator := "?"
+		if strings.Contains(url, "?") {
+			separator = "&"
+		}
+
+		url += separator + "_txlock=immediate&_pragma=busy_timeout(10000)"
+	}
+
+	return sql.Open(dialect.DriverName, url)
+}
+
+func (d *SQLDriver) Bump(b version.Bump) (semver.Version, error) {
+	var newVersion semver.Version
+
+	err := d.inTx(func(tx *sql.Tx) error {
+		currentVersion, exists, err := d.readVersion(tx, d.Dialect.ForUpdate)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			currentVersion = d.InitialVersion
+		}
+
+		newVersion, err = version.Apply(b, currentVersion)
+		if err != nil {
+			return err
+		}
+
+		return d.writeVersion(tx, newVersion)
+	})
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	return newVersion, nil
+}
+
+func (d *SQLDriver) Set(v semver.Version) error {
+	return d.inTx(func(tx *sql.Tx) error {
+		return d.writeVersion(tx, v)
+	})
+}
+
+func (d *SQLDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	v, exists, err := d.readVersion(d.DB, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		if cursor == nil {
+			return []semver.Version{d.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	}
+
+	// Handle a "fly check-resource --from <cursor>" to bring back old versions
+	if cursor != nil {
+		return d.getOldVersions(cursor, v)
+	}
+
+	return []semver.Version{v}, nil
+}
+
+// getOldVersions() goes back through the history table to find all versions
+// newer than the cursor. The newest entry is the write that produced the
+// current version, so the walk starts from the one before it.
+func (d *SQLDriver) getOldVersions(cursor *semver.Version, currentVersion semver.Version) ([]semver.Version, error) {
+	// Supplied cursor version is newer or equal to current, so we do not need to go back in history
+	if cursor.GTE(currentVersion) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	rows, err := d.DB.Query(
+		fmt.Sprintf("SELECT version FROM %s WHERE name = %s ORDER BY id DESC", d.HistoryTable, d.Dialect.Placeholder(1)),
+		d.Name,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	oldVersions := []semver.Version{currentVersion}
+	first := true
+	for rows.Next() {
+		var s string
+		err := rows.Scan(&s)
+		if err != nil {
+			return nil, err
+		}
+
+		if first {
+			first = false
+			continue
+		}
+
+		previousVersion, err := semver.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("parsing number in %s: %s", d.HistoryTable, err)
+		}
+
+		// If cursor is newer than previous version, we've found all versions between cursor and current
+		if cursor.GT(previousVersion) {
+			break
+		}
+
+		// Cursor is older or equal to previous version, so include previous version and continue
+		oldVersions = append(oldVersions, previousVersion)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(oldVersions)
+	return oldVersions, nil
+}
+
+// inTx runs fn in a transaction in which the row for the name exists, so that
+// it can be locked even before the first version is written. Only if the
+// transaction fails for a missing table are the tables created, and the
+// transaction run again, so that writers to existing tables need no privilege
+// to create them.
+func (d *SQLDriver) inTx(fn func(tx *sql.Tx) error) error {
+	err := d.tryTx(fn)
+	if !d.Dialect.IsUndefinedTable(err) {
+		return err
+	}
+
+	err = d.createTables()
+	if err != nil {
+		return err
+	}
+
+	return d.tryTx(fn)
+}
+
+func (d *SQLDriver) tryTx(fn func(tx *sql.Tx) error) error {
+	tx, err := d.DB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(fmt.Sprintf(d.Dialect.InsertIgnore, d.VersionsTable), d.Name)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (d *SQLDriver) createTables() error {
+	_, err := d.DB.Exec(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (name VARCHAR(255) PRIMARY KEY, version VARCHAR(255))",
+		d.VersionsTable,
+	))
+	if err != nil && !d.Dialect.IsExisting(err) {
+		return fmt.Errorf("creating table %s: %w", d.VersionsTable, err)
+	}
+
+	_, err = d.DB.Exec(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (%s, name VARCHAR(255) NOT NULL, version VARCHAR(255) NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)",
+		d.HistoryTable, d.Dialect.HistoryID,
+	))
+	if err != nil && !d.Dialect.IsExisting(err) {
+		return fmt.Errorf("creating table %s: %w", d.HistoryTable, err)
+	}
+
+	_, err = d.DB.Exec(fmt.Sprintf(d.Dialect.CreateHistoryIndex, d.HistoryTable))
+	if err != nil && !d.Dialect.IsExisting(err) {
+		return fmt.Errorf("creating index on table %s: %w", d.HistoryTable, err)
+	}
+
+	return nil
+}
+
+type sqlQueryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// readVersion returns the version stored for the name. A row without a
+// version, or a versions table that has not been created yet, is treated as if
+// the row did not exist.
+func (d *SQLDriver) readVersion(q sqlQueryer, suffix string) (semver.Version, bool, error) {
+	var s sql.NullString
+	err := q.QueryRow(
+		fmt.Sprintf("SELECT version FROM %s WHERE name = %s%s", d.VersionsTable, d.Dialect.Placeholder(1), suffix),
+		d.Name,
+	).Scan(&s)
+	if errors.Is(err, sql.ErrNoRows) || d.Dialect.IsUndefinedTable(err) || (err == nil && !s.Valid) {
+		return semver.Version{}, false, nil
+	}
+
+	if err != nil {
+		return semver.Version{}, false, err
+	}
+
+	v, err := semver.Parse(strings.TrimSpace(s.String))
+	if err != nil {
+		return semver.Version{}, false, fmt.Errorf("parsing number in %s: %s", d.VersionsTable, err)
+	}
+
+	return v, true, nil
+}
+
+func (d *SQLDriver) writeVersion(tx *sql.Tx, v semver.Version) error {
+	_, err := tx.Exec(
+		fmt.Sprintf("UPDATE %s SET version = %s WHERE name = %s", d.VersionsTable, d.Dialect.Placeholder(1), d.Dialect.Placeholder(2)),
+		v.String(), d.Name,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		fmt.Sprintf("INSERT INTO %s (name, version) VALUES (%s, %s)", d.HistoryTable, d.Dialect.Placeholder(1), d.Dialect.Placeholder(2)),
+		d.Name, v.String(),
+	)
+	return err
+}
@@ -0,0 +1,213 @@
+package driver_test
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SQL Driver", func() {
+	var (
+		source models.Source
+		d      *driver.SQLDriver
+	)
+
+	BeforeEach(func() {
+		source = models.Source{
+			DatabaseType: "sqlite",
+			DatabaseURL:  "file:" + filepath.Join(GinkgoT().TempDir(), "semver.db"),
+			Key:          "some-version",
+		}
+
+		var err error
+		d, err = driver.NewSQLDriver(source, semver.Version{Major: 1})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(d.DB.Close)
+	})
+
+	history := func() []string {
+		rows, err := d.DB.Query("SELECT version FROM semver_history WHERE name = ? ORDER BY id", "some-version")
+		Expect(err).NotTo(HaveOccurred())
+		defer rows.Close()
+
+		var versions []string
+		for rows.Next() {
+			var v string
+			Expect(rows.Scan(&v)).To(Succeed())
+			versions = append(versions, v)
+		}
+		Expect(rows.Err()).NotTo(HaveOccurred())
+
+		return versions
+	}
+
+	schema := func(objectType string) []string {
+		rows, err := d.DB.Query("SELECT name FROM sqlite_master WHERE type = ? ORDER BY name", objectType)
+		Expect(err).NotTo(HaveOccurred())
+		defer rows.Close()
+
+		var names []string
+		for rows.Next() {
+			var name string
+			Expect(rows.Scan(&name)).To(Succeed())
+			names = append(names, name)
+		}
+		Expect(rows.Err()).NotTo(HaveOccurred())
+
+		return names
+	}
+
+	Describe("Check", func() {
+		It("returns the initial version without creating the tables when nothing has been written", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+			Expect(schema("table")).To(BeEmpty())
+
+			cursor := semver.MustParse("1.0.0")
+			versions, err = d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(BeEmpty())
+		})
+
+		It("returns the current version", func() {
+			Expect(d.Set(semver.MustParse("2.3.4"))).To(Succeed())
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("keeps versions of different names apart", func() {
+			other, err := driver.NewSQLDriver(models.Source{
+				DatabaseType: source.DatabaseType,
+				DatabaseURL:  source.DatabaseURL,
+				Key:          "other-version",
+			}, semver.Version{})
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(other.DB.Close)
+
+			Expect(d.Set(semver.MustParse("2.3.4"))).To(Succeed())
+			Expect(other.Set(semver.MustParse("9.9.9"))).To(Succeed())
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("returns every version from the cursor onwards, oldest first", func() {
+			for _, v := range []string{"1.0.0", "1.1.0", "1.2.0", "2.0.0"} {
+				Expect(d.Set(semver.MustParse(v))).To(Succeed())
+			}
+
+			cursor := semver.MustParse("1.1.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.1.0"),
+				semver.MustParse("1.2.0"),
+				semver.MustParse("2.0.0"),
+			}))
+		})
+
+		It("returns the current version when the cursor is not older", func() {
+			Expect(d.Set(semver.MustParse("1.0.0"))).To(Succeed())
+			Expect(d.Set(semver.MustParse("2.0.0"))).To(Succeed())
+
+			cursor := semver.MustParse("3.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("bumps the initial version when nothing has been written", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(history()).To(Equal([]string{"1.1.0"}))
+		})
+
+		It("does not lose bumps made concurrently", func() {
+			Expect(d.Set(semver.MustParse("1.0.0"))).To(Succeed())
+
+			var wg sync.WaitGroup
+			for range 10 {
+				wg.Go(func() {
+					defer GinkgoRecover()
+
+					_, err := d.Bump(version.PatchBump{})
+					Expect(err).NotTo(HaveOccurred())
+				})
+			}
+			wg.Wait()
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.10")}))
+			Expect(history()).To(HaveLen(11))
+		})
+	})
+
+	Describe("Set", func() {
+		It("creates the tables and the index of the history", func() {
+			Expect(d.Set(semver.MustParse("1.2.3"))).To(Succeed())
+			Expect(d.Set(semver.MustParse("1.2.4"))).To(Succeed())
+
+			Expect(schema("table")).To(ContainElements("semver_history", "semver_versions"))
+			Expect(schema("index")).To(ContainElement("semver_history_name"))
+		})
+
+		It("leaves the schema alone when the tables exist", func() {
+			_, err := d.DB.Exec("CREATE TABLE semver_versions (name VARCHAR(255) PRIMARY KEY, version VARCHAR(255))")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = d.DB.Exec("CREATE TABLE semver_history (id INTEGER PRIMARY KEY AUTOINCREMENT, name VARCHAR(255) NOT NULL, version VARCHAR(255) NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(d.Set(semver.MustParse("1.2.3"))).To(Succeed())
+
+			Expect(history()).To(Equal([]string{"1.2.3"}))
+			Expect(schema("index")).NotTo(ContainElement("semver_history_name"))
+		})
+
+		It("creates the history table when only the versions table exists", func() {
+			_, err := d.DB.Exec("CREATE TABLE semver_versions (name VARCHAR(255) PRIMARY KEY, version VARCHAR(255))")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(d.Set(semver.MustParse("1.2.3"))).To(Succeed())
+
+			Expect(history()).To(Equal([]string{"1.2.3"}))
+		})
+
+		It("appends every version to the history", func() {
+			Expect(d.Set(semver.MustParse("1.2.3"))).To(Succeed())
+			Expect(d.Set(semver.MustParse("1.0.0"))).To(Succeed())
+
+			Expect(history()).To(Equal([]string{"1.2.3", "1.0.0"}))
+		})
+	})
+
+	Describe("NewSQLDriver", func() {
+		It("rejects unknown database types", func() {
+			source.DatabaseType = "oracle"
+
+			_, err := driver.NewSQLDriver(source, semver.Version{})
+			Expect(err).To(MatchError(ContainSubstring(`"mysql" "postgres" "sqlite"`)))
+		})
+
+		It("rejects table names that are not plain identifiers", func() {
+			source.HistoryTable = "history; DROP TABLE users"
+
+			_, err := driver.NewSQLDriver(source, semver.Version{})
+			Expect(err).To(MatchError(ContainSubstring("invalid table name")))
+		})
+	})
+})
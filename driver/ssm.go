@@ -0,0 +1,275 @@
+package driver
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+)
+
+type SSMServicer interface {
+	GetParameter(context.Context, *ssm.GetParameterInput, ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
+	PutParameter(context.Context, *ssm.PutParameterInput, ...func(*ssm.Options)) (*ssm.PutParameterOutput, error)
+	GetParameterHistory(context.Context, *ssm.GetParameterHistoryInput, ...func(*ssm.Options)) (*ssm.GetParameterHistoryOutput, error)
+	DeleteParameter(context.Context, *ssm.DeleteParameterInput, ...func(*ssm.Options)) (*ssm.DeleteParameterOutput, error)
+}
+
+// SSMDriver stores the version in a String parameter of the AWS Systems
+// Manager Parameter Store. Parameter Store cannot overwrite a parameter
+// conditionally, so writers serialize on a sibling ".lock" parameter instead,
+// which PutParameter only creates if it does not exist yet.
+type SSMDriver struct {
+	InitialVersion semver.Version
+
+	Svc           SSMServicer
+	ParameterName string
+
+	// LockTimeout is how long to wait for another writer to remove the lock
+	// parameter before giving up.
+	LockTimeout time.Duration
+
+	// StaleLockAge is how old a lock parameter has to be to be considered
+	// left behind by an interrupted writer and taken over. Zero never takes
+	// over.
+	StaleLockAge time.Duration
+}
+
+const ssmLockPollInterval = 500 * time.Millisecond
+
+// Bump applies the bump to the current version and writes it back while
+// holding the lock.
+func (d *SSMDriver) Bump(b version.Bump) (semver.Version, error) {
+	unlock, err := d.lock()
+	if err != nil {
+		return semver.Version{}, err
+	}
+	defer unlock()
+
+	currentVersion, _, err := d.readVersion()
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	newVersion, err := version.Apply(b, currentVersion)
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	err = d.writeVersion(newVersion)
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	return newVersion, nil
+}
+
+func (d *SSMDriver) Set(newVersion semver.Version) error {
+	unlock, err := d.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return d.writeVersion(newVersion)
+}
+
+func (d *SSMDriver) writeVersion(newVersion semver.Version) error {
+	_, err := d.Svc.PutParameter(context.TODO(), &ssm.PutParameterInput{
+		Name:      aws.String(d.ParameterName),
+		Value:     aws.String(newVersion.String()),
+		Type:      types.ParameterTypeString,
+		Overwrite: aws.Bool(true),
+	})
+	return err
+}
+
+func (d *SSMDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	currentVersion, parameterVersion, err := d.readVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if parameterVersion == 0 {
+		if cursor == nil {
+			return []semver.Version{d.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	}
+
+	// Handle a "fly check-resource --from <cursor>" to bring back old versions
+	if cursor != nil {
+		return d.getOldVersions(cursor, currentVersion, parameterVersion)
+	}
+
+	return []semver.Version{currentVersion}, nil
+}
+
+// getOldVersions() goes back through the parameter's history to find all
+// versions newer than the cursor, starting from the one before the version
+// Check just read. The loop ends when we find a version older than the cursor
+// or run out of history.
+func (d *SSMDriver) getOldVersions(cursor *semver.Version, currentVersion semver.Version, parameterVersion int64) ([]semver.Version, error) {
+	// Supplied cursor version is newer or equal to current, so we do not need to go back in history
+	if cursor.GTE(currentVersion) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	var history []types.ParameterHistory
+
+	paginator := ssm.NewGetParameterHistoryPaginator(d.Svc, &ssm.GetParameterHistoryInput{
+		Name: aws.String(d.ParameterName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("getting parameter history: %w", err)
+		}
+
+		for _, entry := range page.Parameters {
+			// skip anything written after the version we started from
+			if entry.Version < parameterVersion {
+				history = append(history, entry)
+			}
+		}
+	}
+
+	// newest first
+	slices.SortFunc(history, func(a, b types.ParameterHistory) int {
+		return cmp.Compare(b.Version, a.Version)
+	})
+
+	oldVersions := []semver.Version{currentVersion}
+	for _, entry := range history {
+		previousVersion, err := semver.Parse(strings.TrimSpace(aws.ToString(entry.Value)))
+		if err != nil {
+			return nil, fmt.Errorf("parsing number in version %d of parameter %s: %s", entry.Version, d.ParameterName, err)
+		}
+
+		// If cursor is newer than previous version, we've found all versions between cursor and current
+		if cursor.GT(previousVersion) {
+			break
+		}
+
+		// Cursor is older or equal to previous version, so include previous version and continue
+		oldVersions = append(oldVersions, previousVersion)
+	}
+
+	slices.Reverse(oldVersions)
+	return oldVersions, nil
+}
+
+// readVersion returns the version in the parameter along with the parameter's
+// version number. Parameter versions start at 1, so a version number of 0
+// means that the parameter does not exist.
+func (d *SSMDriver) readVersion() (semver.Version, int64, error) {
+	resp, err := d.Svc.GetParameter(context.TODO(), &ssm.GetParameterInput{
+		Name: aws.String(d.ParameterName),
+	})
+	if err != nil {
+		var notFound *types.ParameterNotFound
+		if errors.As(err, &notFound) {
+			return d.InitialVersion, 0, nil
+		}
+
+		return semver.Version{}, 0, err
+	}
+
+	currentVersion, err := semver.Parse(strings.TrimSpace(aws.ToString(resp.Parameter.Value)))
+	if err != nil {
+		return semver.Version{}, 0, fmt.Errorf("parsing number in parameter %s: %s", d.ParameterName, err)
+	}
+
+	return currentVersion, resp.Parameter.Version, nil
+}
+
+// lock creates the lock parameter with Overwrite off, which fails if it
+// exists, waiting for another writer to remove it first for up to
+// LockTimeout. The lock parameter holds a random token so that only its owner
+// removes it.
+//
+// A lock parameter older than StaleLockAge is removed and the lock taken
+// over. Two writers taking over the same stale lock at once may both get it,
+// so StaleLockAge has to be well above how long any write holds the lock.
+func (d *SSMDriver) lock() (func(), error) {
+	lockName := d.ParameterName + ".lock"
+	deadline := time.Now().Add(d.LockTimeout)
+
+	token, err := lockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		_, err := d.Svc.PutParameter(context.TODO(), &ssm.PutParameterInput{
+			Name:      aws.String(lockName),
+			Value:     aws.String(token),
+			Type:      types.ParameterTypeString,
+			Overwrite: aws.Bool(false),
+		})
+		if err == nil {
+			return func() { d.unlock(lockName, token) }, nil
+		}
+
+		var exists *types.ParameterAlreadyExists
+		if !errors.As(err, &exists) {
+			return nil, fmt.Errorf("creating lock parameter %s: %w", lockName, err)
+		}
+
+		resp, err := d.Svc.GetParameter(context.TODO(), &ssm.GetParameterInput{
+			Name: aws.String(lockName),
+		})
+		var notFound *types.ParameterNotFound
+		if errors.As(err, &notFound) {
+			// removed in between, try again right away
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading lock parameter %s: %w", lockName, err)
+		}
+
+		modified := aws.ToTime(resp.Parameter.LastModifiedDate)
+		if d.StaleLockAge > 0 && time.Since(modified) > d.StaleLockAge {
+			fmt.Fprintf(os.Stderr, "taking over stale lock parameter %s from %s\n", lockName, modified.Format(time.RFC3339))
+
+			_, err = d.Svc.DeleteParameter(context.TODO(), &ssm.DeleteParameterInput{
+				Name: aws.String(lockName),
+			})
+			if err != nil && !errors.As(err, &notFound) {
+				return nil, fmt.Errorf("removing stale lock parameter %s: %w", lockName, err)
+			}
+
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock parameter %s to be removed", lockName)
+		}
+
+		time.Sleep(ssmLockPollInterval)
+	}
+}
+
+// unlock removes the lock parameter, unless it was taken over by another
+// writer in the meantime.
+func (d *SSMDriver) unlock(lockName string, token string) {
+	resp, err := d.Svc.GetParameter(context.TODO(), &ssm.GetParameterInput{
+		Name: aws.String(lockName),
+	})
+	if err != nil || aws.ToString(resp.Parameter.Value) != token {
+		return
+	}
+
+	d.Svc.DeleteParameter(context.TODO(), &ssm.DeleteParameterInput{
+		Name: aws.String(lockName),
+	})
+}
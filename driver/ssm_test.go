@@ -0,0 +1,395 @@
+package driver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SSM Driver", func() {
+	var (
+		store  *fakeParameterStore
+		server *httptest.Server
+		d      *driver.SSMDriver
+	)
+
+	BeforeEach(func() {
+		store = &fakeParameterStore{}
+		server = httptest.NewServer(store)
+		DeferCleanup(server.Close)
+
+		d = &driver.SSMDriver{
+			InitialVersion: semver.Version{Major: 1},
+			Svc: ssm.New(ssm.Options{
+				Region:       "us-east-1",
+				BaseEndpoint: aws.String(server.URL),
+				Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+			}),
+			ParameterName: "/ci/version",
+			LockTimeout:   2 * time.Second,
+		}
+	})
+
+	Describe("Check", func() {
+		It("returns the initial version when the parameter does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns no versions for a cursor when the parameter does not exist", func() {
+			cursor := semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(BeEmpty())
+		})
+
+		It("returns the version in the parameter", func() {
+			store.write("2.3.4")
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+		})
+
+		It("returns an error when the parameter does not contain a version", func() {
+			store.write("bogus")
+
+			_, err := d.Check(nil)
+			Expect(err).To(MatchError(ContainSubstring("parsing number in parameter /ci/version")))
+		})
+
+		It("returns the versions in the history from the cursor on", func() {
+			for _, v := range []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0", "2.0.0"} {
+				store.write(v)
+			}
+
+			cursor := semver.MustParse("1.1.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.1.0"),
+				semver.MustParse("1.2.0"),
+				semver.MustParse("1.3.0"),
+				semver.MustParse("2.0.0"),
+			}))
+		})
+
+		It("returns only the current version when the cursor is not older", func() {
+			store.write("1.0.0")
+			store.write("2.0.0")
+
+			cursor := semver.MustParse("2.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("bumps the initial version when the parameter does not exist", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(store.values()).To(Equal([]string{"1.1.0"}))
+		})
+
+		It("bumps the version in the parameter", func() {
+			store.write("1.2.3")
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(store.values()).To(Equal([]string{"1.2.3", "1.2.4"}))
+		})
+
+		It("waits for the lock parameter to be removed", func() {
+			store.write("1.2.3")
+			store.setLock("someone-else", time.Now())
+
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(time.Second)
+				store.write("1.5.0")
+				store.removeLock()
+			}()
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.5.1"))
+			Expect(store.values()).To(Equal([]string{"1.2.3", "1.5.0", "1.5.1"}))
+			Expect(store.lockHeld()).To(BeFalse())
+		})
+
+		It("gives up when the lock parameter is not removed in time", func() {
+			d.StaleLockAge = time.Hour
+			store.write("1.2.3")
+			store.setLock("someone-else", time.Now())
+
+			_, err := d.Bump(version.PatchBump{})
+			Expect(err).To(MatchError(ContainSubstring("timed out waiting for lock parameter /ci/version.lock")))
+			Expect(store.values()).To(Equal([]string{"1.2.3"}))
+		})
+
+		It("takes over a stale lock parameter", func() {
+			d.StaleLockAge = time.Hour
+			store.write("1.2.3")
+			store.setLock("someone-else", time.Now().Add(-2*time.Hour))
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(store.lockHeld()).To(BeFalse())
+		})
+
+		It("leaves a lock parameter that another writer took over", func() {
+			store.write("1.2.3")
+			store.beforePut = func() {
+				store.lock = "someone-else"
+			}
+
+			_, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.lockHeld()).To(BeTrue())
+		})
+
+		It("serializes concurrent bumps", func() {
+			var wg sync.WaitGroup
+			versions := make(chan string, 5)
+
+			for range 5 {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+
+					newVersion, err := d.Bump(version.PatchBump{})
+					Expect(err).NotTo(HaveOccurred())
+					versions <- newVersion.String()
+				}()
+			}
+
+			wg.Wait()
+			close(versions)
+
+			var bumped []string
+			for v := range versions {
+				bumped = append(bumped, v)
+			}
+
+			Expect(bumped).To(ConsistOf("1.0.1", "1.0.2", "1.0.3", "1.0.4", "1.0.5"))
+			Expect(store.values()).To(HaveLen(5))
+		})
+	})
+
+	Describe("Set", func() {
+		It("creates the parameter", func() {
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.values()).To(Equal([]string{"5.0.0"}))
+		})
+
+		It("overwrites the parameter", func() {
+			store.write("1.2.3")
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.values()).To(Equal([]string{"1.2.3", "5.0.0"}))
+		})
+	})
+})
+
+// fakeParameterStore serves the parts of the SSM API used by the driver for a
+// single parameter, keeping every value written, and its lock parameter.
+// beforePut is called with the store locked right before the parameter is
+// written.
+type fakeParameterStore struct {
+	mu sync.Mutex
+
+	history []string
+
+	lock         string
+	lockModified time.Time
+
+	beforePut func()
+}
+
+func (s *fakeParameterStore) write(value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = append(s.history, value)
+}
+
+func (s *fakeParameterStore) values() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.history
+}
+
+func (s *fakeParameterStore) setLock(token string, modified time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lock = token
+	s.lockModified = modified
+}
+
+func (s *fakeParameterStore) removeLock() {
+	s.setLock("", time.Time{})
+}
+
+func (s *fakeParameterStore) lockHeld() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lock != ""
+}
+
+type fakeParameter struct {
+	Name             string
+	Type             string `json:",omitempty"`
+	Value            string
+	Version          int64
+	LastModifiedDate float64 `json:",omitempty"`
+}
+
+func (s *fakeParameterStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var input struct {
+		Name      string
+		Value     string
+		Overwrite bool
+		NextToken string
+	}
+	err := json.NewDecoder(r.Body).Decode(&input)
+	Expect(err).NotTo(HaveOccurred())
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+
+	target := r.Header.Get("X-Amz-Target")
+	if input.Name == "/ci/version.lock" {
+		s.serveLock(w, target, input.Value, input.Overwrite)
+		return
+	}
+
+	name := input.Name
+	Expect(name).To(Equal("/ci/version"))
+
+	switch target {
+	case "AmazonSSM.GetParameter":
+		version := int64(len(s.history))
+		if version == 0 {
+			s.fail(w, "ParameterNotFound")
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"Parameter": fakeParameter{Name: name, Type: "String", Value: s.history[version-1], Version: version},
+		})
+
+	case "AmazonSSM.PutParameter":
+		if s.beforePut != nil {
+			s.beforePut()
+		}
+
+		if len(s.history) > 0 && !input.Overwrite {
+			s.fail(w, "ParameterAlreadyExists")
+			return
+		}
+
+		s.history = append(s.history, input.Value)
+		json.NewEncoder(w).Encode(map[string]any{"Version": len(s.history)})
+
+	case "AmazonSSM.GetParameterHistory":
+		// two entries per page, oldest first, like the real API
+		start := 0
+		if input.NextToken != "" {
+			start, err = strconv.Atoi(input.NextToken)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		end := min(start+2, len(s.history))
+
+		page := map[string]any{}
+		parameters := []fakeParameter{}
+		for i := start; i < end; i++ {
+			parameters = append(parameters, fakeParameter{Name: name, Value: s.history[i], Version: int64(i + 1)})
+		}
+		page["Parameters"] = parameters
+
+		if end < len(s.history) {
+			page["NextToken"] = strconv.Itoa(end)
+		}
+
+		json.NewEncoder(w).Encode(page)
+
+	default:
+		http.Error(w, "unexpected target", http.StatusBadRequest)
+	}
+}
+
+// serveLock serves the lock parameter, which only exists while lock is set.
+func (s *fakeParameterStore) serveLock(w http.ResponseWriter, target string, value string, overwrite bool) {
+	switch target {
+	case "AmazonSSM.GetParameter":
+		if s.lock == "" {
+			s.fail(w, "ParameterNotFound")
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"Parameter": fakeParameter{
+				Name:             "/ci/version.lock",
+				Type:             "String",
+				Value:            s.lock,
+				Version:          1,
+				LastModifiedDate: float64(s.lockModified.Unix()),
+			},
+		})
+
+	case "AmazonSSM.PutParameter":
+		if s.lock != "" && !overwrite {
+			s.fail(w, "ParameterAlreadyExists")
+			return
+		}
+
+		s.lock = value
+		s.lockModified = time.Now()
+		json.NewEncoder(w).Encode(map[string]any{"Version": 1})
+
+	case "AmazonSSM.DeleteParameter":
+		if s.lock == "" {
+			s.fail(w, "ParameterNotFound")
+			return
+		}
+
+		s.lock = ""
+		json.NewEncoder(w).Encode(map[string]any{})
+
+	default:
+		http.Error(w, "unexpected target", http.StatusBadRequest)
+	}
+}
+
+func (s *fakeParameterStore) fail(w http.ResponseWriter, errorType string) {
+	w.Header().Set("X-Amzn-ErrorType", errorType)
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"__type": errorType, "message": errorType})
+}
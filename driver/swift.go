@@ -2,8 +2,12 @@ package driver
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/blang/semver"
 	"github.com/concourse/semver-resource/models"
@@ -14,13 +18,25 @@ import (
 	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
 )
 
+// SwiftDriver stores the version in an object of a container. Swift ignores
+// If-Match on PUT, so writers serialize on a lock object next to it instead.
 type SwiftDriver struct {
-	Container          string
-	ItemName           string
-	InitialVersion     semver.Version
+	Container      string
+	ItemName       string
+	InitialVersion semver.Version
+
+	// LockTimeout is how long to wait for another writer to remove the lock
+	// object before giving up.
+	LockTimeout time.Duration
+	// LockExpiry is how long Swift keeps a lock object before deleting it,
+	// so that a lock left behind by an interrupted writer is released.
+	LockExpiry time.Duration
+
 	swiftServiceClient *gophercloud.ServiceClient
 }
 
+const swiftLockPollInterval = 500 * time.Millisecond
+
 func NewSwiftDriver(source *models.Source) (Driver, error) {
 	os := source.OpenStack
 	if os.Container == "" {
@@ -77,6 +93,8 @@ func NewSwiftDriver(source *models.Source) (Driver, error) {
 		InitialVersion:     initialVersion,
 		Container:          source.OpenStack.Container,
 		ItemName:           source.OpenStack.ItemName,
+		LockTimeout:        time.Minute,
+		LockExpiry:         10 * time.Minute,
 	}
 
 	return driver, nil
@@ -116,14 +134,26 @@ func createOpts(os models.OpenStackOptions) gophercloud.AuthOptions {
 	return opts
 }
 
+// Bump applies the bump to the current version and writes it back while
+// holding the lock.
 func (driver *SwiftDriver) Bump(bump version.Bump) (semver.Version, error) {
-	currentVersion, err := driver.getCurrentVersion()
+	unlock, err := driver.lock()
+	if err != nil {
+		return semver.Version{}, err
+	}
+	defer unlock()
+
+	currentVersion, _, err := driver.getCurrentVersion()
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	newVersion, err := version.Apply(bump, currentVersion)
 	if err != nil {
 		return semver.Version{}, err
 	}
 
-	newVersion := bump.Apply(currentVersion)
-	err = driver.Set(newVersion)
+	err = driver.create(driver.ItemName, driver.createOpts(newVersion))
 	if err != nil {
 		return semver.Version{}, err
 	}
@@ -132,46 +162,115 @@ func (driver *SwiftDriver) Bump(bump version.Bump) (semver.Version, error) {
 }
 
 func (driver *SwiftDriver) Set(newVersion semver.Version) error {
-	content := strings.NewReader(newVersion.String())
-	opts := objects.CreateOpts{
-		Content:            content,
+	unlock, err := driver.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return driver.create(driver.ItemName, driver.createOpts(newVersion))
+}
+
+func (driver *SwiftDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	itemVersion, _, err := driver.getCurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	return []semver.Version{itemVersion}, nil
+}
+
+func (driver *SwiftDriver) createOpts(newVersion semver.Version) objects.CreateOpts {
+	return objects.CreateOpts{
+		Content:            strings.NewReader(newVersion.String()),
 		ContentDisposition: fmt.Sprintf(`attachment; filename="%s"`, driver.ItemName),
 	}
+}
 
-	// Now execute the upload
-	res := objects.Create(context.TODO(), driver.swiftServiceClient, driver.Container, driver.ItemName, opts)
+func (driver *SwiftDriver) create(objectName string, opts objects.CreateOptsBuilder) error {
+	res := objects.Create(context.TODO(), driver.swiftServiceClient, driver.Container, objectName, opts)
 
 	// We have the option of extracting the resulting headers from the response
 	_, err := res.Extract()
 	return err
 }
 
-func (driver *SwiftDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
-	itemVersion, err := driver.getCurrentVersion()
+// lock creates the lock object with If-None-Match: *, which Swift honours,
+// waiting for another writer to remove it first if it already exists. The
+// lock object holds a random token so that only its owner removes it, and
+// expires after LockExpiry in case its owner is interrupted.
+func (driver *SwiftDriver) lock() (func(), error) {
+	lockName := driver.ItemName + ".lock"
+	deadline := time.Now().Add(driver.LockTimeout)
+
+	token, err := lockToken()
 	if err != nil {
 		return nil, err
 	}
 
-	return []semver.Version{itemVersion}, nil
+	for {
+		err := driver.create(lockName, objects.CreateOpts{
+			Content:     strings.NewReader(token),
+			IfNoneMatch: "*",
+			DeleteAfter: int64(driver.LockExpiry.Seconds()),
+		})
+		if err == nil {
+			return func() { driver.unlock(lockName, token) }, nil
+		}
+
+		if !gophercloud.ResponseCodeIs(err, http.StatusPreconditionFailed) {
+			return nil, fmt.Errorf("creating lock object %s: %w", lockName, err)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock object %s to be removed", lockName)
+		}
+
+		time.Sleep(swiftLockPollInterval)
+	}
+}
+
+// unlock removes the lock object, unless it expired and was taken by another
+// writer in the meantime.
+func (driver *SwiftDriver) unlock(lockName string, token string) {
+	downloader := objects.Download(context.TODO(), driver.swiftServiceClient, driver.Container, lockName, nil)
+	content, err := downloader.ExtractContent()
+	if err != nil || string(content) != token {
+		return
+	}
+
+	objects.Delete(context.TODO(), driver.swiftServiceClient, driver.Container, lockName, nil)
 }
 
-func (driver *SwiftDriver) getCurrentVersion() (semver.Version, error) {
+// lockToken returns a random token identifying the owner of a lock.
+func lockToken() (string, error) {
+	token := make([]byte, 16)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+// getCurrentVersion returns the version stored in the container, or the
+// initial version if the object does not exist yet.
+func (driver *SwiftDriver) getCurrentVersion() (semver.Version, bool, error) {
 	downloader := objects.Download(context.TODO(), driver.swiftServiceClient, driver.Container, driver.ItemName, nil)
 	bytes, err := downloader.ExtractContent()
-	unexpectedResponseCodeError, isType := err.(*gophercloud.ErrUnexpectedResponseCode)
-	if isType && unexpectedResponseCodeError.Actual == 404 {
-		return driver.InitialVersion, nil
+	if gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+		return driver.InitialVersion, false, nil
 	}
 
 	if err != nil {
-		return semver.Version{}, err
+		return semver.Version{}, false, err
 	}
 
 	value := strings.TrimSpace(string(bytes))
 	itemVersion, err := semver.Parse(value)
 	if err != nil {
-		return semver.Version{}, fmt.Errorf("parsing number in container: %s", err)
+		return semver.Version{}, false, fmt.Errorf("parsing number in container: %s", err)
 	}
 
-	return itemVersion, nil
+	return itemVersion, true, nil
 }
@@ -3,7 +3,13 @@ package driver
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/blang/semver"
 	"github.com/concourse/semver-resource/models"
@@ -195,6 +201,199 @@ var _ = Describe("Swift", Ordered, func() {
 	})
 })
 
+var _ = Describe("Swift locking", func() {
+	var (
+		store  *fakeSwiftStore
+		server *httptest.Server
+		driver *SwiftDriver
+	)
+
+	BeforeEach(func() {
+		store = &fakeSwiftStore{objects: map[string]string{}}
+		server = httptest.NewServer(store)
+
+		driver = &SwiftDriver{
+			Container:      "container",
+			ItemName:       "version",
+			InitialVersion: semver.Version{Major: 1},
+			LockTimeout:    2 * time.Second,
+			LockExpiry:     10 * time.Minute,
+			swiftServiceClient: &gophercloud.ServiceClient{
+				ProviderClient: &gophercloud.ProviderClient{},
+				Endpoint:       server.URL + "/",
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("bumps the version while holding the lock object", func() {
+		store.put("version", "1.2.3")
+
+		semVer, err := driver.Bump(version.PatchBump{})
+		Expect(err).To(BeNil())
+		Expect(semVer.String()).To(Equal("1.2.4"))
+		Expect(store.get("version")).To(Equal("1.2.4"))
+		Expect(store.puts).To(Equal([]string{"version.lock If-None-Match: * X-Delete-After: 600", "version"}))
+		Expect(store.has("version.lock")).To(BeFalse())
+	})
+
+	It("bumps the initial version when the object is absent", func() {
+		semVer, err := driver.Bump(version.MinorBump{})
+		Expect(err).To(BeNil())
+		Expect(semVer.String()).To(Equal("1.1.0"))
+		Expect(store.get("version")).To(Equal("1.1.0"))
+	})
+
+	It("serializes concurrent bumps", func() {
+		var wg sync.WaitGroup
+		versions := make(chan string, 5)
+
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				newVersion, err := driver.Bump(version.PatchBump{})
+				Expect(err).NotTo(HaveOccurred())
+				versions <- newVersion.String()
+			}()
+		}
+
+		wg.Wait()
+		close(versions)
+
+		var bumped []string
+		for v := range versions {
+			bumped = append(bumped, v)
+		}
+
+		Expect(bumped).To(ConsistOf("1.0.1", "1.0.2", "1.0.3", "1.0.4", "1.0.5"))
+		Expect(store.get("version")).To(Equal("1.0.5"))
+	})
+
+	It("gives up when the lock object is not removed in time", func() {
+		store.put("version", "1.2.3")
+		store.put("version.lock", "someone-else")
+
+		_, err := driver.Bump(version.PatchBump{})
+		Expect(err).To(MatchError(ContainSubstring("timed out waiting for lock object version.lock")))
+		Expect(store.get("version")).To(Equal("1.2.3"))
+	})
+
+	It("leaves a lock object that another writer took over after it expired", func() {
+		store.put("version", "1.2.3")
+		store.beforePut = func(name string) {
+			if name == "version" {
+				store.objects["version.lock"] = "someone-else"
+			}
+		}
+
+		_, err := driver.Bump(version.PatchBump{})
+		Expect(err).To(BeNil())
+		Expect(store.get("version.lock")).To(Equal("someone-else"))
+	})
+
+	It("takes the lock when setting", func() {
+		store.put("version", "3.0.0")
+
+		err := driver.Set(semver.Version{Major: 2})
+		Expect(err).To(BeNil())
+		Expect(store.get("version")).To(Equal("2.0.0"))
+		Expect(store.puts).To(Equal([]string{"version.lock If-None-Match: * X-Delete-After: 600", "version"}))
+		Expect(store.has("version.lock")).To(BeFalse())
+	})
+})
+
+// fakeSwiftStore serves the objects of a single container, honouring
+// If-None-Match: * on PUT like Swift. beforePut is called right before a PUT
+// is handled, to simulate another writer.
+type fakeSwiftStore struct {
+	mu      sync.Mutex
+	objects map[string]string
+
+	beforePut func(name string)
+	puts      []string
+}
+
+func (s *fakeSwiftStore) put(name, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[name] = body
+}
+
+func (s *fakeSwiftStore) get(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[name]
+}
+
+func (s *fakeSwiftStore) has(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, found := s.objects[name]
+	return found
+}
+
+func (s *fakeSwiftStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := strings.TrimPrefix(r.URL.Path, "/container/")
+	body, exists := s.objects[name]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		io.WriteString(w, body)
+
+	case http.MethodPut:
+		ifNoneMatch := r.Header.Get("If-None-Match")
+
+		put := name
+		if ifNoneMatch != "" {
+			put += " If-None-Match: " + ifNoneMatch
+		}
+		if deleteAfter := r.Header.Get("X-Delete-After"); deleteAfter != "" {
+			put += " X-Delete-After: " + deleteAfter
+		}
+		s.puts = append(s.puts, put)
+
+		if s.beforePut != nil {
+			s.beforePut(name)
+			_, exists = s.objects[name]
+		}
+
+		if ifNoneMatch == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		newBody, _ := io.ReadAll(r.Body)
+		s.objects[name] = string(newBody)
+		w.WriteHeader(http.StatusCreated)
+
+	case http.MethodDelete:
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		delete(s.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func newTestSwiftDriver(initialVersion string, itemName string) (Driver, error) {
 
 	identityEndpoint := os.Getenv("OS_AUTH_URL")
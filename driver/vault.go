@@ -0,0 +1,238 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/hashicorp/vault/api"
+
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+)
+
+// VaultDriver stores the version in a field of a KV v2 secret. Other fields of
+// the secret are left as they are.
+type VaultDriver struct {
+	InitialVersion semver.Version
+
+	Client *api.Client
+	Mount  string
+	Path   string
+	Field  string
+}
+
+func NewVaultClient(source models.Source) (*api.Client, error) {
+	config := api.DefaultConfig()
+	if config.Error != nil {
+		return nil, config.Error
+	}
+
+	config.Address = source.VaultAddress
+
+	if source.SkipSSLVerification {
+		err := config.ConfigureTLS(&api.TLSConfig{Insecure: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client.SetToken(source.VaultToken)
+
+	if source.VaultNamespace != "" {
+		client.SetNamespace(source.VaultNamespace)
+	}
+
+	return client, nil
+}
+
+// Bump writes the bumped version, using the secret version that was read as
+// the check-and-set parameter so that a concurrent write makes it fail and
+// start over.
+func (d *VaultDriver) Bump(b version.Bump) (semver.Version, error) {
+	read := func() (semver.Version, vaultSecret, error) {
+		data, secretVersion, err := d.readSecret()
+		if err != nil {
+			return semver.Version{}, vaultSecret{}, err
+		}
+
+		v, exists, err := d.parseField(data)
+		if err == nil && !exists {
+			v = d.InitialVersion
+		}
+
+		return v, vaultSecret{data, secretVersion}, err
+	}
+
+	return retryOnConflict(b, read, func(newVersion semver.Version, secret vaultSecret) error {
+		newData := maps.Clone(secret.data)
+		if newData == nil {
+			newData = map[string]any{}
+		}
+		newData[d.Field] = newVersion.String()
+
+		_, err := d.kv().Put(context.Background(), d.Path, newData, api.WithCheckAndSet(secret.version))
+		return err
+	}, isVaultCASMismatch)
+}
+
+func (d *VaultDriver) Set(v semver.Version) error {
+	_, err := d.Bump(version.SetBump{Version: v})
+	return err
+}
+
+func (d *VaultDriver) Check(cursor *semver.Version) ([]semver.Version, error) {
+	data, secretVersion, err := d.readSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	v, exists, err := d.parseField(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		if cursor == nil {
+			return []semver.Version{d.InitialVersion}, nil
+		}
+		return []semver.Version{}, nil
+	}
+
+	// Handle a "fly check-resource --from <cursor>" to bring back old versions
+	if cursor != nil {
+		return d.getOldVersions(cursor, v, secretVersion)
+	}
+
+	return []semver.Version{v}, nil
+}
+
+// getOldVersions() goes back through the secret's earlier versions to find all
+// versions newer than the cursor. Deleted and destroyed secret versions are
+// skipped, and the walk stops at a secret version without the field.
+func (d *VaultDriver) getOldVersions(cursor *semver.Version, currentVersion semver.Version, currentSecretVersion int) ([]semver.Version, error) {
+	// Supplied cursor version is newer or equal to current, so we do not need to go back in history
+	if cursor.GTE(currentVersion) {
+		return []semver.Version{currentVersion}, nil
+	}
+
+	secretVersions, err := d.kv().GetVersionsAsList(context.Background(), d.Path)
+	if err != nil {
+		var respErr *api.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden {
+			fmt.Fprintf(os.Stderr, "not permitted to read secret metadata, skipping version history: %s\n", err)
+			return []semver.Version{currentVersion}, nil
+		}
+
+		return nil, fmt.Errorf("listing secret versions: %w", err)
+	}
+
+	slices.Reverse(secretVersions)
+
+	oldVersions := []semver.Version{currentVersion}
+	for _, metadata := range secretVersions {
+		// skip the secret version we started from and anything written since
+		if metadata.Version >= currentSecretVersion {
+			continue
+		}
+
+		if metadata.Destroyed || !metadata.DeletionTime.IsZero() {
+			continue
+		}
+
+		secret, err := d.kv().GetVersion(context.Background(), d.Path, metadata.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		previousVersion, exists, err := d.parseField(secret.Data)
+		if err != nil {
+			return nil, fmt.Errorf("secret version %d: %w", metadata.Version, err)
+		}
+
+		// the field did not exist yet, so nothing older is relevant
+		if !exists {
+			break
+		}
+
+		// If cursor is newer than previous version, we've found all versions between cursor and current
+		if cursor.GT(previousVersion) {
+			break
+		}
+
+		// Cursor is older or equal to previous version, so include previous version and continue
+		oldVersions = append(oldVersions, previousVersion)
+	}
+
+	slices.Reverse(oldVersions)
+	return oldVersions, nil
+}
+
+// vaultSecret is the data of a secret along with its version.
+type vaultSecret struct {
+	data    map[string]any
+	version int
+}
+
+func (d *VaultDriver) kv() *api.KVv2 {
+	return d.Client.KVv2(d.Mount)
+}
+
+// readSecret returns the current data of the secret along with its version,
+// which is 0 if the secret does not exist. The data of a deleted secret is
+// nil, but its version is still returned as writes must check-and-set on it.
+func (d *VaultDriver) readSecret() (map[string]any, int, error) {
+	secret, err := d.kv().Get(context.Background(), d.Path)
+	if errors.Is(err, api.ErrSecretNotFound) {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	if secret.VersionMetadata == nil {
+		return secret.Data, 0, nil
+	}
+
+	return secret.Data, secret.VersionMetadata.Version, nil
+}
+
+func (d *VaultDriver) parseField(data map[string]any) (semver.Version, bool, error) {
+	value, found := data[d.Field]
+	if !found {
+		return semver.Version{}, false, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return semver.Version{}, false, fmt.Errorf("field %s in secret is not a string", d.Field)
+	}
+
+	v, err := semver.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return semver.Version{}, false, fmt.Errorf("parsing number in field %s: %s", d.Field, err)
+	}
+
+	return v, true, nil
+}
+
+func isVaultCASMismatch(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+
+	return slices.ContainsFunc(respErr.Errors, func(e string) bool {
+		return strings.Contains(e, "check-and-set parameter did not match")
+	})
+}
@@ -0,0 +1,290 @@
+package driver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	"github.com/blang/semver"
+
+	"github.com/concourse/semver-resource/driver"
+	"github.com/concourse/semver-resource/models"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vault Driver", func() {
+	var (
+		store *fakeKVStore
+		d     *driver.VaultDriver
+	)
+
+	BeforeEach(func() {
+		d, store = newFakeVaultDriver()
+	})
+
+	Describe("Check", func() {
+		It("returns the initial version when the secret does not exist", func() {
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the initial version when the secret has no version field", func() {
+			store.write(map[string]any{"owner": "release-team"})
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("1.0.0")}))
+		})
+
+		It("returns the version in the field", func() {
+			store.write(map[string]any{"version": "2.3.4"})
+
+			versions, err := d.Check(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.3.4")}))
+			Expect(store.tokens).To(ContainElement("some-token"))
+		})
+
+		It("returns every version from the cursor onwards, oldest first", func() {
+			store.write(map[string]any{"owner": "release-team"})
+			for _, v := range []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0", "2.0.0"} {
+				store.write(map[string]any{"owner": "release-team", "version": v})
+			}
+			store.versions[3].deleted = true
+
+			cursor := semver.MustParse("1.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.0.0"),
+				semver.MustParse("1.1.0"),
+				semver.MustParse("1.3.0"),
+				semver.MustParse("2.0.0"),
+			}))
+		})
+
+		It("stops at the first version older than the cursor", func() {
+			for _, v := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+				store.write(map[string]any{"version": v})
+			}
+
+			cursor := semver.MustParse("1.1.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{
+				semver.MustParse("1.1.0"),
+				semver.MustParse("1.2.0"),
+			}))
+		})
+
+		It("returns the current version when the cursor is not older", func() {
+			store.write(map[string]any{"version": "1.0.0"})
+			store.write(map[string]any{"version": "2.0.0"})
+
+			cursor := semver.MustParse("3.0.0")
+			versions, err := d.Check(&cursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versions).To(Equal([]semver.Version{semver.MustParse("2.0.0")}))
+		})
+	})
+
+	Describe("Bump", func() {
+		It("only creates the secret if it still does not exist", func() {
+			newVersion, err := d.Bump(version.MinorBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.1.0"))
+			Expect(store.current()).To(Equal(map[string]any{"version": "1.1.0"}))
+			Expect(store.cas).To(Equal([]int{0}))
+		})
+
+		It("keeps the other fields of the secret", func() {
+			store.write(map[string]any{"owner": "release-team", "version": "1.2.3"})
+
+			newVersion, err := d.Bump(version.PatchBump{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newVersion.String()).To(Equal("1.2.4"))
+			Expect(store.current()).To(Equal(map[string]any{"owner": "release-team", "version": "1.2.4"}))
+			Expect(store.cas).To(Equal([]int{1}))
+		})
+	})
+
+	Describe("Set", func() {
+		It("writes the version with check-and-set", func() {
+			store.write(map[string]any{"owner": "release-team", "version": "1.2.3"})
+
+			err := d.Set(semver.MustParse("5.0.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.current()).To(Equal(map[string]any{"owner": "release-team", "version": "5.0.0"}))
+			Expect(store.cas).To(Equal([]int{1}))
+		})
+	})
+})
+
+type fakeKVVersion struct {
+	data    map[string]any
+	deleted bool
+}
+
+// fakeKVStore serves a single secret from a KV v2 secrets engine, enforcing
+// check-and-set on writes.
+type fakeKVStore struct {
+	mount string
+	path  string
+
+	versions []fakeKVVersion
+
+	concurrentWrites []string
+	cas              []int
+	tokens           []string
+}
+
+func (s *fakeKVStore) write(data map[string]any) {
+	s.versions = append(s.versions, fakeKVVersion{data: data})
+}
+
+func (s *fakeKVStore) current() map[string]any {
+	return s.versions[len(s.versions)-1].data
+}
+
+func (s *fakeKVStore) metadata(version int) map[string]any {
+	deletionTime := ""
+	if s.versions[version-1].deleted {
+		deletionTime = time.Now().Format(time.RFC3339)
+	}
+
+	return map[string]any{
+		"version":       version,
+		"created_time":  time.Now().Format(time.RFC3339),
+		"deletion_time": deletionTime,
+		"destroyed":     false,
+	}
+}
+
+func (s *fakeKVStore) respond(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (s *fakeKVStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.tokens = append(s.tokens, r.Header.Get("X-Vault-Token"))
+
+	switch r.URL.Path {
+	case "/v1/" + s.mount + "/data/" + s.path:
+		switch r.Method {
+		case http.MethodGet:
+			version := len(s.versions)
+			if v := r.URL.Query().Get("version"); v != "" {
+				version, _ = strconv.Atoi(v)
+			}
+
+			if version == 0 {
+				s.respond(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+				return
+			}
+
+			stored := s.versions[version-1]
+			if stored.deleted {
+				s.respond(w, http.StatusNotFound, map[string]any{
+					"data": map[string]any{"data": nil, "metadata": s.metadata(version)},
+				})
+				return
+			}
+
+			s.respond(w, http.StatusOK, map[string]any{
+				"data": map[string]any{"data": stored.data, "metadata": s.metadata(version)},
+			})
+
+		case http.MethodPut, http.MethodPost:
+			var req struct {
+				Data    map[string]any `json:"data"`
+				Options struct {
+					CAS *int `json:"cas"`
+				} `json:"options"`
+			}
+			err := json.NewDecoder(r.Body).Decode(&req)
+			if err != nil {
+				s.respond(w, http.StatusBadRequest, map[string]any{"errors": []string{err.Error()}})
+				return
+			}
+
+			if req.Options.CAS != nil {
+				s.cas = append(s.cas, *req.Options.CAS)
+			}
+
+			if len(s.concurrentWrites) > 0 {
+				s.write(map[string]any{"version": s.concurrentWrites[0]})
+				s.concurrentWrites = s.concurrentWrites[1:]
+			}
+
+			if req.Options.CAS != nil && *req.Options.CAS != len(s.versions) {
+				s.respond(w, http.StatusBadRequest, map[string]any{
+					"errors": []string{"check-and-set parameter did not match the current version"},
+				})
+				return
+			}
+
+			s.write(req.Data)
+			s.respond(w, http.StatusOK, map[string]any{"data": s.metadata(len(s.versions))})
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	case "/v1/" + s.mount + "/metadata/" + s.path:
+		if len(s.versions) == 0 {
+			s.respond(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+			return
+		}
+
+		versions := map[string]any{}
+		for i := range s.versions {
+			metadata := s.metadata(i + 1)
+			delete(metadata, "version")
+			versions[strconv.Itoa(i+1)] = metadata
+		}
+
+		s.respond(w, http.StatusOK, map[string]any{
+			"data": map[string]any{"current_version": len(s.versions), "versions": versions},
+		})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeVaultDriver() (*driver.VaultDriver, *fakeKVStore) {
+	store := &fakeKVStore{mount: "secret", path: "some/release"}
+	server := httptest.NewServer(store)
+	DeferCleanup(server.Close)
+
+	client, err := driver.NewVaultClient(models.Source{
+		VaultAddress: server.URL,
+		VaultToken:   "some-token",
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	return &driver.VaultDriver{
+		InitialVersion: semver.Version{Major: 1},
+		Client:         client,
+		Mount:          "secret",
+		Path:           "some/release",
+		Field:          "version",
+	}, store
+}
+
+func vaultCASSubject() casSubject {
+	d, store := newFakeVaultDriver()
+
+	return casSubject{
+		driver:  d,
+		store:   func(v string) { store.write(map[string]any{"version": v}) },
+		current: func() string { return store.current()["version"].(string) },
+		race:    func(versions ...string) { store.concurrentWrites = versions },
+	}
+}
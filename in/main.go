@@ -36,13 +36,24 @@ func main() {
 		fatal("parsing semantic version", err)
 	}
 
-	bumped := version.BumpFromParams(
-		request.Params.Bump,
-		request.Params.Pre,
-		request.Params.PreWithoutVersion,
-		request.Params.Build,
-		request.Params.BuildWithoutVersion,
-	).Apply(inputVersion)
+	bump, err := version.BumpFromParams(version.BumpParams{
+		Bump:                request.Params.Bump,
+		Pre:                 request.Params.Pre,
+		PreWithoutVersion:   request.Params.PreWithoutVersion,
+		Build:               request.Params.Build,
+		BuildWithoutVersion: request.Params.BuildWithoutVersion,
+		CalVerFormat:        request.Params.CalVerFormat,
+		CalVerTimezone:      request.Params.CalVerTimezone,
+		PreReleaseChannels:  request.Source.PreReleaseChannels,
+	})
+	if err != nil {
+		fatal("reading bump params", err)
+	}
+
+	bumped, err := version.Apply(bump, inputVersion)
+	if err != nil {
+		fatal("bumping version", err)
+	}
 
 	if !bumped.Equals(inputVersion) {
 		fmt.Fprintf(os.Stderr, "bumped locally from %s to %s\n", inputVersion, bumped)
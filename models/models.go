@@ -21,6 +21,8 @@ type InParams struct {
 	Build               string `json:"build"`
 	PreWithoutVersion   bool   `json:"pre_without_version"`
 	BuildWithoutVersion bool   `json:"build_without_version"`
+	CalVerFormat        string `json:"calver_format"`
+	CalVerTimezone      string `json:"calver_timezone"`
 }
 
 type OutRequest struct {
@@ -42,6 +44,13 @@ type OutParams struct {
 	Build               string `json:"build"`
 	PreWithoutVersion   bool   `json:"pre_without_version"`
 	BuildWithoutVersion bool   `json:"build_without_version"`
+	CalVerFormat        string `json:"calver_format"`
+	CalVerTimezone      string `json:"calver_timezone"`
+
+	Repository string `json:"repository"`
+	TagPrefix  string `json:"tag_prefix"`
+
+	EnforceIncreasing bool `json:"enforce_increasing"`
 
 	GetLatest bool `json:"get_latest,omitempty"`
 }
@@ -56,7 +65,9 @@ type CheckResponse []Version
 type Source struct {
 	Driver Driver `json:"driver"`
 
-	InitialVersion string `json:"initial_version"`
+	InitialVersion     string   `json:"initial_version"`
+	PreReleaseChannels []string `json:"pre_release_channels"`
+	EnforceIncreasing  bool     `json:"enforce_increasing"`
 
 	Bucket               string `json:"bucket"`
 	Key                  string `json:"key"`
@@ -80,11 +91,67 @@ type Source struct {
 	File          string `json:"file"`
 	GitUser       string `json:"git_user"`
 	CommitMessage string `json:"commit_message"`
+	TagPrefix     string `json:"tag_prefix"`
 
 	OpenStack OpenStackOptions `json:"openstack"`
 
 	JSONKey  string `json:"json_key"`
 	GCSToken string `json:"token"`
+
+	Path string `json:"path"`
+
+	StorageAccount    string `json:"storage_account"`
+	StorageAccountKey string `json:"storage_account_key"`
+	SASToken          string `json:"sas_token"`
+	TenantID          string `json:"tenant_id"`
+	ClientID          string `json:"client_id"`
+	ClientSecret      string `json:"client_secret"`
+	Container         string `json:"container"`
+
+	VaultAddress   string `json:"vault_address"`
+	VaultToken     string `json:"vault_token"`
+	VaultNamespace string `json:"vault_namespace"`
+	VaultMount     string `json:"vault_mount"`
+	VaultPath      string `json:"vault_path"`
+	VaultField     string `json:"vault_field"`
+
+	ConsulAddress    string `json:"consul_address"`
+	ConsulToken      string `json:"consul_token"`
+	ConsulDatacenter string `json:"consul_datacenter"`
+
+	EtcdEndpoints []string `json:"etcd_endpoints"`
+	EtcdUsername  string   `json:"etcd_username"`
+	EtcdPassword  string   `json:"etcd_password"`
+
+	RedisAddress  string `json:"redis_address"`
+	RedisDatabase int    `json:"redis_database"`
+	RedisUsername string `json:"redis_username"`
+	RedisPassword string `json:"redis_password"`
+	RedisTLS      bool   `json:"redis_tls"`
+
+	DatabaseType  string `json:"database_type"`
+	DatabaseURL   string `json:"database_url"`
+	VersionsTable string `json:"versions_table"`
+	HistoryTable  string `json:"history_table"`
+
+	Kubeconfig   string `json:"kubeconfig"`
+	Namespace    string `json:"namespace"`
+	ConfigMap    string `json:"config_map"`
+	ConfigMapKey string `json:"config_map_key"`
+
+	DynamoDBTable            string `json:"dynamodb_table"`
+	DynamoDBKeyAttribute     string `json:"dynamodb_key_attribute"`
+	DynamoDBVersionAttribute string `json:"dynamodb_version_attribute"`
+
+	BearerToken string            `json:"bearer_token"`
+	Headers     map[string]string `json:"headers"`
+	CACert      string            `json:"ca_cert"`
+
+	Host    string `json:"host"`
+	HostKey string `json:"host_key"`
+
+	Repository string `json:"repository"`
+	FromTag    string `json:"from_tag"`
 }
 
 // OpenStackOptions contains properties for authenticating and accessing
@@ -134,6 +201,20 @@ const (
 	DriverUnspecified Driver = ""
 	DriverS3          Driver = "s3"
 	DriverGit         Driver = "git"
+	DriverGitTags     Driver = "git-tags"
 	DriverSwift       Driver = "swift"
 	DriverGCS         Driver = "gcs"
+	DriverFile        Driver = "file"
+	DriverAzure       Driver = "azure"
+	DriverVault       Driver = "vault"
+	DriverConsul      Driver = "consul"
+	DriverEtcd        Driver = "etcd"
+	DriverRedis       Driver = "redis"
+	DriverSQL         Driver = "sql"
+	DriverKubernetes  Driver = "kubernetes"
+	DriverSSM         Driver = "ssm"
+	DriverDynamoDB    Driver = "dynamodb"
+	DriverHTTP        Driver = "http"
+	DriverSFTP        Driver = "sftp"
+	DriverOCI         Driver = "oci"
 )
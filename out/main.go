@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -27,6 +28,10 @@ func main() {
 		fatal("reading request", err)
 	}
 
+	if request.Params.EnforceIncreasing {
+		request.Source.EnforceIncreasing = true
+	}
+
 	driver, err := driver.FromSource(request.Source)
 	if err != nil {
 		fatal("constructing driver", err)
@@ -58,18 +63,63 @@ func main() {
 			fatal("parsing version", err)
 		}
 
-		err = driver.Set(newVersion)
+		channels := version.PreReleaseChannels(request.Source.PreReleaseChannels)
+		if len(channels) > 0 {
+			// check the channels against the version that is replaced
+			_, err = driver.Bump(version.GuardBump{Bump: version.SetBump{Version: newVersion}, Guard: channels.CheckOrder})
+		} else {
+			err = driver.Set(newVersion)
+		}
 		if err != nil {
 			fatal("setting version", err)
 		}
 	} else if request.Params.Bump != "" || request.Params.Pre != "" || request.Params.Build != "" {
-		bump := version.BumpFromParams(
-			request.Params.Bump,
-			request.Params.Pre,
-			request.Params.PreWithoutVersion,
-			request.Params.Build,
-			request.Params.BuildWithoutVersion,
-		)
+		bumpParam := request.Params.Bump
+
+		// for the auto bump, the commits decide the semantic bump, on top of
+		// which pre and build are applied as usual
+		var autoBump version.AutoBump
+		if bumpParam == "auto" {
+			if request.Params.Repository == "" {
+				fatal("reading bump params", errors.New("must specify repository for the auto bump"))
+			}
+
+			autoBump, err = version.AutoBumpFromRepository(filepath.Join(sources, request.Params.Repository), request.Params.TagPrefix)
+			if err != nil {
+				fatal("reading commits", err)
+			}
+
+			bumpParam = ""
+		}
+
+		paramsBump, err := version.BumpFromParams(version.BumpParams{
+			Bump:                bumpParam,
+			Pre:                 request.Params.Pre,
+			PreWithoutVersion:   request.Params.PreWithoutVersion,
+			Build:               request.Params.Build,
+			BuildWithoutVersion: request.Params.BuildWithoutVersion,
+			CalVerFormat:        request.Params.CalVerFormat,
+			CalVerTimezone:      request.Params.CalVerTimezone,
+			PreReleaseChannels:  request.Source.PreReleaseChannels,
+		})
+		if err != nil {
+			fatal("reading bump params", err)
+		}
+
+		var bump version.Bump = version.MultiBump{autoBump, paramsBump}
+
+		channels := version.PreReleaseChannels(request.Source.PreReleaseChannels)
+		if len(channels) > 0 {
+			bump = version.GuardBump{Bump: bump, Guard: channels.CheckOrder}
+		}
+
+		if request.Params.Bump == "auto" && autoBump.Change() == version.NoChange && request.Params.Pre == "" && request.Params.Build == "" {
+			fmt.Fprintln(os.Stderr, "no commits since the last release call for a bump, keeping the current version")
+
+			// still go through the driver, which resolves the initial version
+			// and lets enforce_increasing through for an unchanged version
+			bump = version.IdentityBump{}
+		}
 
 		newVersion, err = driver.Bump(bump)
 		if err != nil {
@@ -0,0 +1,204 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Change is how significant the changes made by commits are.
+type Change int
+
+const (
+	NoChange Change = iota
+	PatchChange
+	MinorChange
+	MajorChange
+)
+
+// AutoBump bumps the version by the most significant change among commit
+// messages following https://www.conventionalcommits.org: a breaking change
+// bumps the major version, a feature the minor version and a fix the patch
+// version. Before 1.0.0, a breaking change bumps the minor version and a
+// feature the patch version instead. Without any of those, the version is left
+// as it is.
+type AutoBump struct {
+	Messages []string
+}
+
+// conventionalHeader matches the type, scope and breaking change marker of
+// the first line of a commit message, e.g. "feat(api)!: drop v1".
+var conventionalHeader = regexp.MustCompile(`^(\w+)(\([^)]*\))?(!)?: `)
+
+func (bump AutoBump) Apply(v semver.Version) semver.Version {
+	change := bump.Change()
+
+	if v.Major == 0 && change > PatchChange {
+		change--
+	}
+
+	switch change {
+	case MajorChange:
+		return MajorBump{}.Apply(v)
+	case MinorChange:
+		return MinorBump{}.Apply(v)
+	case PatchChange:
+		return PatchBump{}.Apply(v)
+	default:
+		return v
+	}
+}
+
+// Change returns the most significant change made by the commits.
+func (bump AutoBump) Change() Change {
+	change := NoChange
+	for _, message := range bump.Messages {
+		change = max(change, commitChange(message))
+	}
+
+	return change
+}
+
+func commitChange(message string) Change {
+	header, body, _ := strings.Cut(message, "\n")
+
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "BREAKING CHANGE: ") || strings.HasPrefix(line, "BREAKING-CHANGE: ") {
+			return MajorChange
+		}
+	}
+
+	match := conventionalHeader.FindStringSubmatch(header)
+	if match == nil {
+		return NoChange
+	}
+
+	if match[3] == "!" {
+		return MajorChange
+	}
+
+	switch strings.ToLower(match[1]) {
+	case "feat":
+		return MinorChange
+	case "fix":
+		return PatchChange
+	default:
+		return NoChange
+	}
+}
+
+// AutoBumpFromRepository reads the messages of the commits in the local
+// repository at path that were made since the last release, i.e. that are
+// reachable from HEAD but not from a tag named tagPrefix followed by a
+// version. It fails without such a tag, as the commits counted by earlier
+// bumps cannot be told apart then, and if a shallow clone ends before reaching
+// one, as the commits made since the last release cannot all be read then.
+func AutoBumpFromRepository(path string, tagPrefix string) (AutoBump, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return AutoBump{}, fmt.Errorf("opening repository %s: %w", path, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return AutoBump{}, fmt.Errorf("resolving HEAD of %s: %w", path, err)
+	}
+
+	tags, err := repo.Tags()
+	if err != nil {
+		return AutoBump{}, fmt.Errorf("listing tags of %s: %w", path, err)
+	}
+
+	var released []plumbing.Hash
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		tag, found := strings.CutPrefix(ref.Name().Short(), tagPrefix)
+		if !found {
+			return nil
+		}
+
+		if _, err := semver.Parse(tag); err != nil {
+			return nil
+		}
+
+		// annotated tags point to a tag object rather than the commit
+		hash, err := repo.ResolveRevision(plumbing.Revision(ref.Name()))
+		if err != nil {
+			return fmt.Errorf("resolving tag %s: %w", ref.Name().Short(), err)
+		}
+
+		released = append(released, *hash)
+		return nil
+	})
+	if err != nil {
+		return AutoBump{}, err
+	}
+
+	if len(released) == 0 {
+		return AutoBump{}, fmt.Errorf("no version tag with prefix %q found in %s: tag the last release, e.g. %s1.0.0, so that its commits are not counted again", tagPrefix, path, tagPrefix)
+	}
+
+	// history missing behind the tags is irrelevant, as it is released too
+	releasedCommits, _, err := walkCommits(repo, released, nil)
+	if err != nil {
+		return AutoBump{}, err
+	}
+
+	unreleasedCommits, missing, err := walkCommits(repo, []plumbing.Hash{head.Hash()}, releasedCommits)
+	if err != nil {
+		return AutoBump{}, err
+	}
+
+	if len(missing) > 0 {
+		return AutoBump{}, fmt.Errorf("commit %s is missing from %s before reaching a release tag: fetch the repository with enough history to reach the last release", missing[0], path)
+	}
+
+	var bump AutoBump
+	for _, commit := range unreleasedCommits {
+		bump.Messages = append(bump.Messages, commit.Message)
+	}
+
+	return bump, nil
+}
+
+// walkCommits returns the commits reachable from the given ones, stopping at
+// the commits in skip, along with the commits it could not read because they
+// are missing from a shallow clone.
+func walkCommits(repo *git.Repository, from []plumbing.Hash, skip map[plumbing.Hash]*object.Commit) (map[plumbing.Hash]*object.Commit, []plumbing.Hash, error) {
+	commits := map[plumbing.Hash]*object.Commit{}
+	var missing []plumbing.Hash
+	queue := from
+
+	for len(queue) > 0 {
+		hash := queue[0]
+		queue = queue[1:]
+
+		if _, found := commits[hash]; found {
+			continue
+		}
+
+		if _, found := skip[hash]; found {
+			continue
+		}
+
+		commit, err := repo.CommitObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			missing = append(missing, hash)
+			continue
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading commit %s: %w", hash, err)
+		}
+
+		commits[hash] = commit
+		queue = append(queue, commit.ParentHashes...)
+	}
+
+	return commits, missing, nil
+}
@@ -0,0 +1,158 @@
+package version_test
+
+import (
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AutoBump", func() {
+	DescribeTable("Apply",
+		func(input string, messages []string, output string) {
+			bump := version.AutoBump{Messages: messages}
+			Expect(bump.Apply(semver.MustParse(input)).String()).To(Equal(output))
+		},
+		Entry("without commits", "1.2.3", nil, "1.2.3"),
+		Entry("with a fix", "1.2.3", []string{"fix: handle empty files\n"}, "1.2.4"),
+		Entry("with a feature", "1.2.3", []string{"fix: typo\n", "feat(api): add endpoint\n"}, "1.3.0"),
+		Entry("with a breaking change marker", "1.2.3", []string{"feat!: drop v1\n", "fix: typo\n"}, "2.0.0"),
+		Entry("with a scoped breaking change marker", "1.2.3", []string{"refactor(api)!: rename fields\n"}, "2.0.0"),
+		Entry("with a breaking change footer", "1.2.3", []string{"fix: parse dates\n\nBREAKING CHANGE: dates must be RFC 3339\n"}, "2.0.0"),
+		Entry("with a hyphenated breaking change footer", "1.2.3", []string{"chore: bump go\n\nBREAKING-CHANGE: go 1.23 is required\n"}, "2.0.0"),
+		Entry("with uppercase types", "1.2.3", []string{"FEAT: shout\n"}, "1.3.0"),
+		Entry("with other types only", "1.2.3", []string{"docs: explain\n", "chore: tidy\n", "Merge branch 'main'\n"}, "1.2.3"),
+		Entry("with a type in the body only", "1.2.3", []string{"update readme\n\nfeat: not really\n"}, "1.2.3"),
+		Entry("before 1.0.0 with a breaking change", "0.4.2", []string{"feat!: drop v1\n"}, "0.5.0"),
+		Entry("before 1.0.0 with a feature", "0.4.2", []string{"feat: add endpoint\n"}, "0.4.3"),
+		Entry("before 1.0.0 with a fix", "0.4.2", []string{"fix: typo\n"}, "0.4.3"),
+		Entry("from a prerelease", "1.2.3-rc.1", []string{"fix: typo\n"}, "1.2.4"),
+	)
+
+	Describe("AutoBumpFromRepository", func() {
+		var (
+			path string
+			repo *git.Repository
+		)
+
+		BeforeEach(func() {
+			path = GinkgoT().TempDir()
+
+			var err error
+			repo, err = git.PlainInit(path, false)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		commit := func(message string) plumbing.Hash {
+			worktree, err := repo.Worktree()
+			Expect(err).NotTo(HaveOccurred())
+
+			hash, err := worktree.Commit(message, &git.CommitOptions{
+				AllowEmptyCommits: true,
+				Author:            &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			return hash
+		}
+
+		tag := func(name string, hash plumbing.Hash, annotated bool) {
+			var opts *git.CreateTagOptions
+			if annotated {
+				opts = &git.CreateTagOptions{
+					Tagger:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+					Message: name,
+				}
+			}
+
+			_, err := repo.CreateTag(name, hash, opts)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		It("returns an error without version tags", func() {
+			commit("fix: first\n")
+			tag("latest", commit("feat: second\n"), false)
+
+			_, err := version.AutoBumpFromRepository(path, "v")
+			Expect(err).To(MatchError(ContainSubstring(`no version tag with prefix "v" found`)))
+		})
+
+		It("reads the commits since the last version tag", func() {
+			commit("feat!: released\n")
+			released := commit("feat: released too\n")
+			tag("v1.0.0", released, true)
+			tag("latest", commit("fix: unreleased\n"), false)
+			commit("docs: unreleased\n")
+
+			bump, err := version.AutoBumpFromRepository(path, "v")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bump.Messages).To(ConsistOf("fix: unreleased\n", "docs: unreleased\n"))
+			Expect(bump.Change()).To(Equal(version.PatchChange))
+		})
+
+		It("only considers tags with the prefix", func() {
+			tag("1.0.0", commit("feat: released without prefix\n"), false)
+			commit("fix: unreleased\n")
+
+			_, err := version.AutoBumpFromRepository(path, "release-")
+			Expect(err).To(HaveOccurred())
+
+			bump, err := version.AutoBumpFromRepository(path, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bump.Messages).To(ConsistOf("fix: unreleased\n"))
+		})
+
+		It("finds no commits on a tagged HEAD", func() {
+			tag("v1.0.0", commit("feat: released\n"), false)
+
+			bump, err := version.AutoBumpFromRepository(path, "v")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bump.Messages).To(BeEmpty())
+			Expect(bump.Change()).To(Equal(version.NoChange))
+		})
+
+		Describe("in a shallow clone", func() {
+			shallowClone := func(depth int) string {
+				clonePath := GinkgoT().TempDir()
+
+				_, err := git.PlainClone(clonePath, false, &git.CloneOptions{
+					URL:   path,
+					Depth: depth,
+					Tags:  git.AllTags,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				return clonePath
+			}
+
+			BeforeEach(func() {
+				commit("feat!: released long ago\n")
+				tag("v1.0.0", commit("feat: released\n"), false)
+				commit("fix: first\n")
+				commit("fix: second\n")
+			})
+
+			It("reads the commits since the last version tag when it is reached", func() {
+				bump, err := version.AutoBumpFromRepository(shallowClone(3), "v")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(bump.Messages).To(ConsistOf("fix: first\n", "fix: second\n"))
+			})
+
+			It("returns an error when no version tag is reached", func() {
+				_, err := version.AutoBumpFromRepository(shallowClone(1), "v")
+				Expect(err).To(MatchError(ContainSubstring("before reaching a release tag")))
+			})
+		})
+
+		It("returns an error when the path is not a repository", func() {
+			_, err := version.AutoBumpFromRepository(GinkgoT().TempDir(), "v")
+			Expect(err).To(MatchError(ContainSubstring("opening repository")))
+		})
+	})
+})
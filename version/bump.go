@@ -6,6 +6,22 @@ type Bump interface {
 	Apply(semver.Version) semver.Version
 }
 
+// CheckedBump is a Bump that can refuse to apply to a version.
+type CheckedBump interface {
+	Bump
+	CheckedApply(semver.Version) (semver.Version, error)
+}
+
+// Apply applies the bump to the version, returning the error of a CheckedBump
+// that refuses to.
+func Apply(bump Bump, v semver.Version) (semver.Version, error) {
+	if checked, ok := bump.(CheckedBump); ok {
+		return checked.CheckedApply(v)
+	}
+
+	return bump.Apply(v), nil
+}
+
 type IdentityBump struct{}
 
 func (IdentityBump) Apply(v semver.Version) semver.Version {
@@ -1,9 +1,33 @@
 package version
 
-func BumpFromParams(bumpStr string, preStr string, preWithoutVersion bool, buildStr string, BuildWithoutVersion bool) Bump {
+import (
+	"fmt"
+	"time"
+
+	// the resource's image may not ship a time zone database
+	_ "time/tzdata"
+)
+
+// BumpParams are the params of get and put that bump the version, along with
+// the pre-release channels of the source.
+type BumpParams struct {
+	Bump                string
+	Pre                 string
+	PreWithoutVersion   bool
+	Build               string
+	BuildWithoutVersion bool
+	CalVerFormat        string
+	CalVerTimezone      string
+	PreReleaseChannels  []string
+}
+
+func BumpFromParams(params BumpParams) (Bump, error) {
 	var semverBump Bump
 
-	switch bumpStr {
+	// the npm-style bumps and promote start or bump the prerelease themselves
+	bumpsPre := false
+
+	switch params.Bump {
 	case "major":
 		semverBump = MajorBump{}
 	case "minor":
@@ -12,6 +36,38 @@ func BumpFromParams(bumpStr string, preStr string, preWithoutVersion bool, build
 		semverBump = PatchBump{}
 	case "final":
 		semverBump = FinalBump{}
+	case "premajor":
+		semverBump = PreMajorBump{params.Pre, params.PreWithoutVersion}
+		bumpsPre = true
+	case "preminor":
+		semverBump = PreMinorBump{params.Pre, params.PreWithoutVersion}
+		bumpsPre = true
+	case "prepatch":
+		semverBump = PrePatchBump{params.Pre, params.PreWithoutVersion}
+		bumpsPre = true
+	case "prerelease":
+		semverBump = PrereleaseBump{params.Pre, params.PreWithoutVersion}
+		bumpsPre = true
+	case "promote":
+		if len(params.PreReleaseChannels) == 0 {
+			return nil, fmt.Errorf("must specify pre_release_channels in the source to promote")
+		}
+
+		if params.Pre != "" {
+			return nil, fmt.Errorf("cannot specify pre with the promote bump, which moves to the next of the pre_release_channels")
+		}
+
+		semverBump = PromoteBump{params.PreReleaseChannels, params.PreWithoutVersion}
+		bumpsPre = true
+	case "auto":
+		return nil, fmt.Errorf("the auto bump reads the commits of a repository, so it is only supported by put")
+	case "calver":
+		calverBump, err := calverBumpFromParams(params.CalVerFormat, params.CalVerTimezone)
+		if err != nil {
+			return nil, err
+		}
+
+		semverBump = calverBump
 	}
 
 	var bump MultiBump
@@ -19,12 +75,33 @@ func BumpFromParams(bumpStr string, preStr string, preWithoutVersion bool, build
 		bump = append(bump, semverBump)
 	}
 
-	if preStr != "" {
-		bump = append(bump, PreBump{preStr, preWithoutVersion})
+	if params.Pre != "" && !bumpsPre {
+		bump = append(bump, PreBump{params.Pre, params.PreWithoutVersion})
 	}
 
-	if buildStr != "" {
-		bump = append(bump, BuildBump{buildStr, BuildWithoutVersion})
+	if params.Build != "" {
+		bump = append(bump, BuildBump{params.Build, params.BuildWithoutVersion})
 	}
-	return bump
+	return bump, nil
+}
+
+func calverBumpFromParams(format string, timezone string) (CalVerBump, error) {
+	if format == "" {
+		return CalVerBump{}, fmt.Errorf("must specify calver_format to bump calver")
+	}
+
+	segments, err := ParseCalVerFormat(format)
+	if err != nil {
+		return CalVerBump{}, err
+	}
+
+	location := time.UTC
+	if timezone != "" {
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return CalVerBump{}, fmt.Errorf("invalid calver_timezone: %w", err)
+		}
+	}
+
+	return CalVerBump{Format: segments, Location: location}, nil
 }
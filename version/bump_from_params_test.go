@@ -2,6 +2,7 @@ package version_test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/blang/semver"
 	. "github.com/concourse/semver-resource/version"
@@ -19,6 +20,12 @@ var _ = Describe("BumpForParams", func() {
 		buildParam               string
 		preWithoutVersionParam   bool
 		buildWithoutVersionParam bool
+		calverFormatParam        string
+		calverTimezoneParam      string
+		channelsParam            []string
+
+		bump    Bump
+		bumpErr error
 	)
 
 	BeforeEach(func() {
@@ -33,10 +40,25 @@ var _ = Describe("BumpForParams", func() {
 		buildParam = ""
 		preWithoutVersionParam = false
 		buildWithoutVersionParam = false
+		calverFormatParam = ""
+		calverTimezoneParam = ""
+		channelsParam = nil
 	})
 
 	JustBeforeEach(func() {
-		version = BumpFromParams(bumpParam, preParam, preWithoutVersionParam, buildParam, buildWithoutVersionParam).Apply(version)
+		bump, bumpErr = BumpFromParams(BumpParams{
+			Bump:                bumpParam,
+			Pre:                 preParam,
+			PreWithoutVersion:   preWithoutVersionParam,
+			Build:               buildParam,
+			BuildWithoutVersion: buildWithoutVersionParam,
+			CalVerFormat:        calverFormatParam,
+			CalVerTimezone:      calverTimezoneParam,
+			PreReleaseChannels:  channelsParam,
+		})
+		if bumpErr == nil {
+			version = bump.Apply(version)
+		}
 	})
 
 	for bump, result := range map[string]string{
@@ -173,4 +195,165 @@ var _ = Describe("BumpForParams", func() {
 			})
 		}
 	})
+
+	Context("when bumping npm-style to a prerelease", func() {
+		BeforeEach(func() {
+			preParam = "rc"
+		})
+
+		for bump, result := range map[string]string{
+			"premajor":   "2.0.0-rc.1",
+			"preminor":   "1.3.0-rc.1",
+			"prepatch":   "1.2.4-rc.1",
+			"prerelease": "1.2.4-rc.1",
+		} {
+			bumpLocal := bump
+			resultLocal := result
+
+			Context(fmt.Sprintf("when bumping %s", bumpLocal), func() {
+				BeforeEach(func() {
+					bumpParam = bumpLocal
+				})
+
+				It("bumps to "+resultLocal, func() {
+					Expect(version.String()).To(Equal(resultLocal))
+				})
+			})
+		}
+
+		Context("when it's already a prerelease", func() {
+			BeforeEach(func() {
+				version.Pre = []semver.PRVersion{
+					{VersionStr: "rc"},
+					{VersionNum: 1, IsNum: true},
+				}
+			})
+
+			for bump, result := range map[string]string{
+				"premajor":   "2.0.0-rc.1",
+				"preminor":   "1.3.0-rc.1",
+				"prepatch":   "1.2.4-rc.1",
+				"prerelease": "1.2.3-rc.2",
+			} {
+				bumpLocal := bump
+				resultLocal := result
+
+				Context(fmt.Sprintf("when bumping %s", bumpLocal), func() {
+					BeforeEach(func() {
+						bumpParam = bumpLocal
+					})
+
+					It("bumps to "+resultLocal, func() {
+						Expect(version.String()).To(Equal(resultLocal))
+					})
+				})
+			}
+		})
+	})
+
+	Context("when promoting", func() {
+		BeforeEach(func() {
+			bumpParam = "promote"
+			channelsParam = []string{"alpha", "beta", "rc"}
+
+			version.Pre = []semver.PRVersion{
+				{VersionStr: "beta"},
+				{VersionNum: 3, IsNum: true},
+			}
+		})
+
+		It("bumps to the next channel", func() {
+			Expect(bumpErr).NotTo(HaveOccurred())
+			Expect(version.String()).To(Equal("1.2.3-rc.1"))
+		})
+
+		Context("with a pre param", func() {
+			BeforeEach(func() {
+				preParam = "alpha"
+			})
+
+			It("returns an error", func() {
+				Expect(bumpErr).To(MatchError(ContainSubstring("cannot specify pre with the promote bump")))
+			})
+		})
+
+		Context("without channels", func() {
+			BeforeEach(func() {
+				channelsParam = nil
+			})
+
+			It("returns an error", func() {
+				Expect(bumpErr).To(MatchError("must specify pre_release_channels in the source to promote"))
+			})
+		})
+	})
+
+	Context("when bumping auto", func() {
+		BeforeEach(func() {
+			bumpParam = "auto"
+		})
+
+		It("returns an error, as only put reads the commits", func() {
+			Expect(bumpErr).To(MatchError(ContainSubstring("only supported by put")))
+		})
+	})
+
+	Context("when bumping calver", func() {
+		BeforeEach(func() {
+			bumpParam = "calver"
+			calverFormatParam = "YYYY.0M.MICRO"
+			calverTimezoneParam = "Europe/Berlin"
+		})
+
+		It("bumps with the format in the time zone", func() {
+			Expect(bumpErr).NotTo(HaveOccurred())
+			Expect(bump).To(HaveLen(1))
+
+			calverBump, ok := bump.(MultiBump)[0].(CalVerBump)
+			Expect(ok).To(BeTrue())
+			Expect(calverBump.Format).To(Equal([]CalVerSegment{CalVerFullYear, CalVerZeroPaddedMonth, CalVerMicro}))
+			Expect(calverBump.Location.String()).To(Equal("Europe/Berlin"))
+		})
+
+		Context("without a time zone", func() {
+			BeforeEach(func() {
+				calverTimezoneParam = ""
+			})
+
+			It("bumps in UTC", func() {
+				Expect(bumpErr).NotTo(HaveOccurred())
+				Expect(bump.(MultiBump)[0].(CalVerBump).Location).To(Equal(time.UTC))
+			})
+		})
+
+		Context("without a format", func() {
+			BeforeEach(func() {
+				calverFormatParam = ""
+			})
+
+			It("returns an error", func() {
+				Expect(bumpErr).To(MatchError("must specify calver_format to bump calver"))
+			})
+		})
+
+		Context("with an invalid format", func() {
+			BeforeEach(func() {
+				calverFormatParam = "YYYY.MM"
+			})
+
+			It("returns an error", func() {
+				Expect(bumpErr).To(MatchError(ContainSubstring("must have three dot-separated segments")))
+			})
+		})
+
+		Context("with an unknown time zone", func() {
+			BeforeEach(func() {
+				calverTimezoneParam = "Mars/Olympus_Mons"
+			})
+
+			It("returns an error", func() {
+				Expect(bumpErr).To(MatchError(ContainSubstring("invalid calver_timezone")))
+			})
+		})
+	})
 })
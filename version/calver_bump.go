@@ -0,0 +1,143 @@
+package version
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+)
+
+// CalVerSegment is one of the dot-separated parts of a calendar version
+// layout, following https://calver.org.
+type CalVerSegment string
+
+const (
+	CalVerFullYear        CalVerSegment = "YYYY"
+	CalVerShortYear       CalVerSegment = "YY"
+	CalVerZeroPaddedYear  CalVerSegment = "0Y"
+	CalVerMonth           CalVerSegment = "MM"
+	CalVerZeroPaddedMonth CalVerSegment = "0M"
+	CalVerWeek            CalVerSegment = "WW"
+	CalVerZeroPaddedWeek  CalVerSegment = "0W"
+	CalVerDay             CalVerSegment = "DD"
+	CalVerZeroPaddedDay   CalVerSegment = "0D"
+	CalVerMicro           CalVerSegment = "MICRO"
+)
+
+var calVerSegments = []CalVerSegment{
+	CalVerFullYear, CalVerShortYear, CalVerZeroPaddedYear,
+	CalVerMonth, CalVerZeroPaddedMonth,
+	CalVerWeek, CalVerZeroPaddedWeek,
+	CalVerDay, CalVerZeroPaddedDay,
+	CalVerMicro,
+}
+
+// ParseCalVerFormat parses a layout such as "YYYY.MM.MICRO" or "YY.0M.DD".
+// A version only has three numbers, so the layout must have exactly three
+// segments, at most one of which is MICRO.
+func ParseCalVerFormat(format string) ([]CalVerSegment, error) {
+	parts := strings.Split(format, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("calver format %q must have three dot-separated segments", format)
+	}
+
+	segments := make([]CalVerSegment, len(parts))
+	micros := 0
+	for i, part := range parts {
+		segment := CalVerSegment(part)
+		if !slices.Contains(calVerSegments, segment) {
+			return nil, fmt.Errorf("unknown segment %q in calver format %q. Must be one of: %q", part, format, calVerSegments)
+		}
+
+		if segment == CalVerMicro {
+			micros++
+		}
+
+		segments[i] = segment
+	}
+
+	if micros > 1 {
+		return nil, fmt.Errorf("calver format %q must have at most one MICRO segment", format)
+	}
+
+	return segments, nil
+}
+
+// CalVerBump sets the date segments of the version from the current date in
+// Location. The MICRO segment counts the bumps made on the same date: it is
+// incremented while the date segments stay the same, and reset to 0 when they
+// change. Without a MICRO segment, bumping again on the same date leaves the
+// version as it is.
+//
+// Numbers in a version cannot have leading zeros, so the zero-padded segments
+// produce the same numbers as their unpadded counterparts.
+type CalVerBump struct {
+	Format   []CalVerSegment
+	Location *time.Location
+
+	// Now returns the current time; time.Now is used if it is nil.
+	Now func() time.Time
+}
+
+func (bump CalVerBump) Apply(v semver.Version) semver.Version {
+	now := time.Now
+	if bump.Now != nil {
+		now = bump.Now
+	}
+
+	location := bump.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	date := now().In(location)
+
+	current := []uint64{v.Major, v.Minor, v.Patch}
+	next := make([]uint64, len(current))
+
+	sameDate := true
+	micro := -1
+
+	for i, segment := range bump.Format {
+		if segment == CalVerMicro {
+			micro = i
+			continue
+		}
+
+		next[i] = segment.value(date)
+		if next[i] != current[i] {
+			sameDate = false
+		}
+	}
+
+	if micro >= 0 && sameDate {
+		next[micro] = current[micro] + 1
+	}
+
+	if micro < 0 && sameDate {
+		return v
+	}
+
+	return semver.Version{Major: next[0], Minor: next[1], Patch: next[2]}
+}
+
+func (segment CalVerSegment) value(date time.Time) uint64 {
+	switch segment {
+	case CalVerFullYear:
+		return uint64(date.Year())
+	case CalVerShortYear, CalVerZeroPaddedYear:
+		return uint64(date.Year() - 2000)
+	case CalVerMonth, CalVerZeroPaddedMonth:
+		return uint64(date.Month())
+	case CalVerWeek, CalVerZeroPaddedWeek:
+		// weeks are counted from the start of the year, so that they never
+		// belong to a different year than the date
+		return uint64((date.YearDay()-1)/7 + 1)
+	case CalVerDay, CalVerZeroPaddedDay:
+		return uint64(date.Day())
+	default:
+		return 0
+	}
+}
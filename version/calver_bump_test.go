@@ -0,0 +1,154 @@
+package version_test
+
+import (
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CalVerBump", func() {
+	var inputVersion semver.Version
+	var bump version.CalVerBump
+	var outputVersion semver.Version
+
+	BeforeEach(func() {
+		inputVersion = semver.Version{
+			Major: 2026,
+			Minor: 10,
+			Patch: 3,
+		}
+
+		format, err := version.ParseCalVerFormat("YYYY.MM.MICRO")
+		Expect(err).NotTo(HaveOccurred())
+
+		bump = version.CalVerBump{
+			Format: format,
+			Now: func() time.Time {
+				return time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		outputVersion = bump.Apply(inputVersion)
+	})
+
+	Context("when the date is unchanged", func() {
+		It("increments the micro segment", func() {
+			Expect(outputVersion.String()).To(Equal("2026.10.4"))
+		})
+
+		Context("and the input is a prerelease", func() {
+			BeforeEach(func() {
+				inputVersion.Pre = []semver.PRVersion{{VersionStr: "rc"}}
+				inputVersion.Build = []string{"ci"}
+			})
+
+			It("drops the prerelease and build metadata", func() {
+				Expect(outputVersion.String()).To(Equal("2026.10.4"))
+			})
+		})
+	})
+
+	Context("when the date has changed", func() {
+		BeforeEach(func() {
+			inputVersion.Minor = 9
+		})
+
+		It("sets the date and resets the micro segment", func() {
+			Expect(outputVersion.String()).To(Equal("2026.10.0"))
+		})
+	})
+
+	Context("when the input is not a calendar version", func() {
+		BeforeEach(func() {
+			inputVersion = semver.Version{Major: 1, Minor: 2, Patch: 3}
+		})
+
+		It("switches to a calendar version", func() {
+			Expect(outputVersion.String()).To(Equal("2026.10.0"))
+		})
+	})
+
+	Context("when the micro segment comes first", func() {
+		BeforeEach(func() {
+			format, err := version.ParseCalVerFormat("MICRO.YY.0M")
+			Expect(err).NotTo(HaveOccurred())
+			bump.Format = format
+
+			inputVersion = semver.Version{Major: 7, Minor: 26, Patch: 10}
+		})
+
+		It("increments it in place", func() {
+			Expect(outputVersion.String()).To(Equal("8.26.10"))
+		})
+	})
+
+	Context("when the format has no micro segment", func() {
+		BeforeEach(func() {
+			format, err := version.ParseCalVerFormat("YY.0W.0D")
+			Expect(err).NotTo(HaveOccurred())
+			bump.Format = format
+		})
+
+		It("sets the date", func() {
+			Expect(outputVersion.String()).To(Equal("26.42.18"))
+		})
+
+		Context("and the date is unchanged", func() {
+			BeforeEach(func() {
+				inputVersion = semver.MustParse("26.42.18-rc.1")
+			})
+
+			It("leaves the version alone", func() {
+				Expect(outputVersion.String()).To(Equal("26.42.18-rc.1"))
+			})
+		})
+	})
+
+	Context("with a location", func() {
+		BeforeEach(func() {
+			location, err := time.LoadLocation("Pacific/Auckland")
+			Expect(err).NotTo(HaveOccurred())
+			bump.Location = location
+
+			bump.Now = func() time.Time {
+				return time.Date(2026, time.October, 31, 12, 0, 0, 0, time.UTC)
+			}
+		})
+
+		It("uses the date in the location", func() {
+			Expect(outputVersion.String()).To(Equal("2026.11.0"))
+		})
+	})
+})
+
+var _ = Describe("ParseCalVerFormat", func() {
+	It("parses the segments", func() {
+		format, err := version.ParseCalVerFormat("0Y.WW.MICRO")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(format).To(Equal([]version.CalVerSegment{
+			version.CalVerZeroPaddedYear,
+			version.CalVerWeek,
+			version.CalVerMicro,
+		}))
+	})
+
+	It("requires three segments", func() {
+		_, err := version.ParseCalVerFormat("YYYY.MM.DD.MICRO")
+		Expect(err).To(MatchError(`calver format "YYYY.MM.DD.MICRO" must have three dot-separated segments`))
+	})
+
+	It("rejects unknown segments", func() {
+		_, err := version.ParseCalVerFormat("YYYY.Month.MICRO")
+		Expect(err).To(MatchError(ContainSubstring(`unknown segment "Month"`)))
+	})
+
+	It("rejects more than one micro segment", func() {
+		_, err := version.ParseCalVerFormat("YYYY.MICRO.MICRO")
+		Expect(err).To(MatchError(`calver format "YYYY.MICRO.MICRO" must have at most one MICRO segment`))
+	})
+})
@@ -0,0 +1,31 @@
+package version
+
+import "github.com/blang/semver"
+
+// GuardBump refuses to apply the bump when Guard returns an error for the
+// version it moves from and the version it moves to, e.g. to keep a release
+// from moving back to an earlier pre-release channel.
+type GuardBump struct {
+	Bump  Bump
+	Guard func(from semver.Version, to semver.Version) error
+}
+
+// Apply applies the bump without the guard, as it cannot refuse to. Use
+// CheckedApply, or the Apply function, instead.
+func (bump GuardBump) Apply(v semver.Version) semver.Version {
+	return bump.Bump.Apply(v)
+}
+
+func (bump GuardBump) CheckedApply(v semver.Version) (semver.Version, error) {
+	newVersion, err := Apply(bump.Bump, v)
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	err = bump.Guard(v, newVersion)
+	if err != nil {
+		return semver.Version{}, err
+	}
+
+	return newVersion, nil
+}
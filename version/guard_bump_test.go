@@ -0,0 +1,60 @@
+package version_test
+
+import (
+	"errors"
+
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GuardBump", func() {
+	var (
+		guarded [][2]string
+		refuse  error
+		bump    version.GuardBump
+	)
+
+	BeforeEach(func() {
+		guarded = nil
+		refuse = nil
+
+		bump = version.GuardBump{
+			Bump: version.MinorBump{},
+			Guard: func(from semver.Version, to semver.Version) error {
+				guarded = append(guarded, [2]string{from.String(), to.String()})
+				return refuse
+			},
+		}
+	})
+
+	It("applies the bump when the guard accepts it", func() {
+		newVersion, err := version.Apply(bump, semver.MustParse("1.2.3"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newVersion.String()).To(Equal("1.3.0"))
+		Expect(guarded).To(Equal([][2]string{{"1.2.3", "1.3.0"}}))
+	})
+
+	It("returns the error of the guard", func() {
+		refuse = errors.New("not today")
+
+		_, err := version.Apply(bump, semver.MustParse("1.2.3"))
+		Expect(err).To(MatchError("not today"))
+	})
+
+	It("refuses without guarding when the bump itself refuses", func() {
+		bump.Bump = version.PromoteBump{Channels: version.PreReleaseChannels{"rc"}}
+
+		_, err := version.Apply(bump, semver.MustParse("1.2.3"))
+		Expect(err).To(MatchError(ContainSubstring("already a final version")))
+		Expect(guarded).To(BeEmpty())
+	})
+
+	It("is checked inside of a MultiBump", func() {
+		refuse = errors.New("not today")
+
+		_, err := version.Apply(version.MultiBump{version.PatchBump{}, bump}, semver.MustParse("1.2.3"))
+		Expect(err).To(MatchError("not today"))
+	})
+})
@@ -11,3 +11,15 @@ func (bumps MultiBump) Apply(v semver.Version) semver.Version {
 
 	return v
 }
+
+func (bumps MultiBump) CheckedApply(v semver.Version) (semver.Version, error) {
+	for _, bump := range bumps {
+		var err error
+		v, err = Apply(bump, v)
+		if err != nil {
+			return semver.Version{}, err
+		}
+	}
+
+	return v, nil
+}
@@ -27,3 +27,24 @@ func (bump PreBump) Apply(v semver.Version) semver.Version {
 
 	return v
 }
+
+// newPre returns the prerelease a version starts with when it first becomes a
+// prerelease: the identifier followed by 1, or just the number without one.
+func newPre(pre string, preWithoutVersion bool) []semver.PRVersion {
+	if pre == "" {
+		return []semver.PRVersion{
+			{VersionNum: 1, IsNum: true},
+		}
+	}
+
+	if preWithoutVersion {
+		return []semver.PRVersion{
+			{VersionStr: pre},
+		}
+	}
+
+	return []semver.PRVersion{
+		{VersionStr: pre},
+		{VersionNum: 1, IsNum: true},
+	}
+}
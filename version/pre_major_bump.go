@@ -0,0 +1,16 @@
+package version
+
+import "github.com/blang/semver"
+
+// PreMajorBump bumps the major version number and starts a prerelease of it,
+// like npm's premajor, e.g. 1.2.3 -> 2.0.0-rc.1.
+type PreMajorBump struct {
+	Pre               string
+	PreWithoutVersion bool
+}
+
+func (bump PreMajorBump) Apply(v semver.Version) semver.Version {
+	v = MajorBump{}.Apply(v)
+	v.Pre = newPre(bump.Pre, bump.PreWithoutVersion)
+	return v
+}
@@ -0,0 +1,19 @@
+package version_test
+
+import (
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("PreMajorBump",
+	func(input string, bump version.PreMajorBump, output string) {
+		Expect(bump.Apply(semver.MustParse(input)).String()).To(Equal(output))
+	},
+	Entry("from a final version", "1.2.3", version.PreMajorBump{Pre: "rc"}, "2.0.0-rc.1"),
+	Entry("from a prerelease", "1.2.3-rc.4", version.PreMajorBump{Pre: "rc"}, "2.0.0-rc.1"),
+	Entry("from a prerelease of the next major", "2.0.0-rc.1", version.PreMajorBump{Pre: "rc"}, "3.0.0-rc.1"),
+	Entry("without a version number", "1.2.3", version.PreMajorBump{Pre: "SNAPSHOT", PreWithoutVersion: true}, "2.0.0-SNAPSHOT"),
+	Entry("without an identifier", "1.2.3", version.PreMajorBump{}, "2.0.0-1"),
+)
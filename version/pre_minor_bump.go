@@ -0,0 +1,16 @@
+package version
+
+import "github.com/blang/semver"
+
+// PreMinorBump bumps the minor version number and starts a prerelease of it,
+// like npm's preminor, e.g. 1.2.3 -> 1.3.0-rc.1.
+type PreMinorBump struct {
+	Pre               string
+	PreWithoutVersion bool
+}
+
+func (bump PreMinorBump) Apply(v semver.Version) semver.Version {
+	v = MinorBump{}.Apply(v)
+	v.Pre = newPre(bump.Pre, bump.PreWithoutVersion)
+	return v
+}
@@ -0,0 +1,19 @@
+package version_test
+
+import (
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("PreMinorBump",
+	func(input string, bump version.PreMinorBump, output string) {
+		Expect(bump.Apply(semver.MustParse(input)).String()).To(Equal(output))
+	},
+	Entry("from a final version", "1.2.3", version.PreMinorBump{Pre: "rc"}, "1.3.0-rc.1"),
+	Entry("from a prerelease", "1.2.3-rc.4", version.PreMinorBump{Pre: "rc"}, "1.3.0-rc.1"),
+	Entry("from a prerelease of the next minor", "1.3.0-rc.1", version.PreMinorBump{Pre: "rc"}, "1.4.0-rc.1"),
+	Entry("without a version number", "1.2.3", version.PreMinorBump{Pre: "SNAPSHOT", PreWithoutVersion: true}, "1.3.0-SNAPSHOT"),
+	Entry("without an identifier", "1.2.3", version.PreMinorBump{}, "1.3.0-1"),
+)
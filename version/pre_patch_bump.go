@@ -0,0 +1,16 @@
+package version
+
+import "github.com/blang/semver"
+
+// PrePatchBump bumps the patch version number and starts a prerelease of it,
+// like npm's prepatch, e.g. 1.2.3 -> 1.2.4-rc.1.
+type PrePatchBump struct {
+	Pre               string
+	PreWithoutVersion bool
+}
+
+func (bump PrePatchBump) Apply(v semver.Version) semver.Version {
+	v = PatchBump{}.Apply(v)
+	v.Pre = newPre(bump.Pre, bump.PreWithoutVersion)
+	return v
+}
@@ -0,0 +1,19 @@
+package version_test
+
+import (
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("PrePatchBump",
+	func(input string, bump version.PrePatchBump, output string) {
+		Expect(bump.Apply(semver.MustParse(input)).String()).To(Equal(output))
+	},
+	Entry("from a final version", "1.2.3", version.PrePatchBump{Pre: "rc"}, "1.2.4-rc.1"),
+	Entry("from a prerelease", "1.2.3-rc.4", version.PrePatchBump{Pre: "rc"}, "1.2.4-rc.1"),
+	Entry("from a prerelease of the next patch", "1.2.4-rc.1", version.PrePatchBump{Pre: "rc"}, "1.2.5-rc.1"),
+	Entry("without a version number", "1.2.3", version.PrePatchBump{Pre: "SNAPSHOT", PreWithoutVersion: true}, "1.2.4-SNAPSHOT"),
+	Entry("without an identifier", "1.2.3", version.PrePatchBump{}, "1.2.4-1"),
+)
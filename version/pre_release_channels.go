@@ -0,0 +1,73 @@
+package version
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/blang/semver"
+)
+
+// PreReleaseChannels are the prerelease identifiers a release moves through
+// on its way to a final version, in order, e.g. alpha, beta and rc.
+type PreReleaseChannels []string
+
+// Promote moves a prerelease to the first prerelease of the next channel, or
+// to the final version from the last channel.
+func (channels PreReleaseChannels) Promote(v semver.Version, preWithoutVersion bool) (semver.Version, error) {
+	if len(v.Pre) == 0 {
+		return semver.Version{}, fmt.Errorf("cannot promote %s: it is already a final version", v)
+	}
+
+	i, found := channels.index(v)
+	if !found {
+		return semver.Version{}, fmt.Errorf("cannot promote %s: its prerelease is not one of the channels %q", v, []string(channels))
+	}
+
+	if i == len(channels)-1 {
+		return FinalBump{}.Apply(v), nil
+	}
+
+	v.Pre = newPre(channels[i+1], preWithoutVersion)
+	return v, nil
+}
+
+// CheckOrder returns an error if moving from one version to another would
+// move a release back to an earlier channel, e.g. from 1.0.0-rc.3 to
+// 1.0.0-alpha.1. Prereleases outside of the channels are not checked.
+func (channels PreReleaseChannels) CheckOrder(from semver.Version, to semver.Version) error {
+	if from.Major != to.Major || from.Minor != to.Minor || from.Patch != to.Patch {
+		return nil
+	}
+
+	fromIndex, fromFound := channels.index(from)
+	toIndex, toFound := channels.index(to)
+	if !fromFound || !toFound || toIndex >= fromIndex {
+		return nil
+	}
+
+	return fmt.Errorf("refusing to move %s back to %s: %s comes before %s in the channels %q",
+		from, to, channels.name(toIndex), channels.name(fromIndex), []string(channels))
+}
+
+// index returns the position of the channel of the version, where a final
+// version comes after every channel.
+func (channels PreReleaseChannels) index(v semver.Version) (int, bool) {
+	if len(v.Pre) == 0 {
+		return len(channels), true
+	}
+
+	if v.Pre[0].IsNum {
+		return 0, false
+	}
+
+	i := slices.Index(channels, v.Pre[0].VersionStr)
+	return i, i >= 0
+}
+
+func (channels PreReleaseChannels) name(index int) string {
+	if index == len(channels) {
+		return "final"
+	}
+
+	return channels[index]
+}
@@ -0,0 +1,58 @@
+package version_test
+
+import (
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PreReleaseChannels", func() {
+	channels := version.PreReleaseChannels{"alpha", "beta", "rc"}
+
+	DescribeTable("Promote",
+		func(input string, preWithoutVersion bool, output string) {
+			promoted, err := channels.Promote(semver.MustParse(input), preWithoutVersion)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(promoted.String()).To(Equal(output))
+		},
+		Entry("from the first channel", "1.0.0-alpha.3", false, "1.0.0-beta.1"),
+		Entry("from a middle channel", "1.0.0-beta.1", false, "1.0.0-rc.1"),
+		Entry("from the last channel", "1.0.0-rc.2", false, "1.0.0"),
+		Entry("keeping build metadata", "1.0.0-beta.1+ci.4", false, "1.0.0-rc.1+ci.4"),
+		Entry("without a version number", "1.0.0-alpha", true, "1.0.0-beta"),
+	)
+
+	It("refuses to promote a final version", func() {
+		_, err := channels.Promote(semver.MustParse("1.0.0"), false)
+		Expect(err).To(MatchError("cannot promote 1.0.0: it is already a final version"))
+	})
+
+	It("refuses to promote a prerelease outside of the channels", func() {
+		_, err := channels.Promote(semver.MustParse("1.0.0-dev.1"), false)
+		Expect(err).To(MatchError(`cannot promote 1.0.0-dev.1: its prerelease is not one of the channels ["alpha" "beta" "rc"]`))
+	})
+
+	DescribeTable("CheckOrder allows",
+		func(from string, to string) {
+			Expect(channels.CheckOrder(semver.MustParse(from), semver.MustParse(to))).To(Succeed())
+		},
+		Entry("bumping within a channel", "1.0.0-beta.1", "1.0.0-beta.2"),
+		Entry("moving to a later channel", "1.0.0-alpha.4", "1.0.0-rc.1"),
+		Entry("moving to the final version", "1.0.0-rc.3", "1.0.0"),
+		Entry("starting the next release", "1.0.0", "1.1.0-alpha.1"),
+		Entry("moving to an earlier channel of another release", "1.0.0-rc.3", "1.0.1-alpha.1"),
+		Entry("moving to a prerelease outside of the channels", "1.0.0-rc.3", "1.0.0-dev.1"),
+		Entry("moving from a prerelease outside of the channels", "1.0.0-dev.1", "1.0.0-alpha.1"),
+	)
+
+	DescribeTable("CheckOrder refuses",
+		func(from string, to string, message string) {
+			Expect(channels.CheckOrder(semver.MustParse(from), semver.MustParse(to))).To(MatchError(message))
+		},
+		Entry("moving to an earlier channel", "1.0.0-rc.3", "1.0.0-alpha.1",
+			`refusing to move 1.0.0-rc.3 back to 1.0.0-alpha.1: alpha comes before rc in the channels ["alpha" "beta" "rc"]`),
+		Entry("moving from the final version", "1.0.0", "1.0.0-rc.4",
+			`refusing to move 1.0.0 back to 1.0.0-rc.4: rc comes before final in the channels ["alpha" "beta" "rc"]`),
+	)
+})
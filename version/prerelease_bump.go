@@ -0,0 +1,36 @@
+package version
+
+import "github.com/blang/semver"
+
+// PrereleaseBump behaves like npm's prerelease: a final version is bumped like
+// PrePatchBump, e.g. 1.2.3 -> 1.2.4-rc.1, while a prerelease is bumped like
+// PreBump, e.g. 1.2.4-rc.1 -> 1.2.4-rc.2. Without Pre, the last number of the
+// prerelease is incremented instead, e.g. 1.2.4-rc.1.alpha -> 1.2.4-rc.2.alpha.
+type PrereleaseBump struct {
+	Pre               string
+	PreWithoutVersion bool
+}
+
+func (bump PrereleaseBump) Apply(v semver.Version) semver.Version {
+	if len(v.Pre) == 0 {
+		return PrePatchBump(bump).Apply(v)
+	}
+
+	if bump.Pre != "" {
+		return PreBump(bump).Apply(v)
+	}
+
+	pre := make([]semver.PRVersion, len(v.Pre))
+	copy(pre, v.Pre)
+
+	for i := len(pre) - 1; i >= 0; i-- {
+		if pre[i].IsNum {
+			pre[i].VersionNum++
+			v.Pre = pre
+			return v
+		}
+	}
+
+	v.Pre = append(pre, semver.PRVersion{VersionNum: 1, IsNum: true})
+	return v
+}
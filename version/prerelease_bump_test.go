@@ -0,0 +1,24 @@
+package version_test
+
+import (
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("PrereleaseBump",
+	func(input string, bump version.PrereleaseBump, output string) {
+		Expect(bump.Apply(semver.MustParse(input)).String()).To(Equal(output))
+	},
+	Entry("from a final version", "1.2.3", version.PrereleaseBump{Pre: "rc"}, "1.2.4-rc.1"),
+	Entry("from a prerelease", "1.2.4-rc.1", version.PrereleaseBump{Pre: "rc"}, "1.2.4-rc.2"),
+	Entry("from a prerelease of another type", "1.2.4-alpha.3", version.PrereleaseBump{Pre: "beta"}, "1.2.4-beta.1"),
+	Entry("from a prerelease without a version number", "1.2.4-rc", version.PrereleaseBump{Pre: "rc"}, "1.2.4-rc.1"),
+	Entry("without a version number", "1.2.3", version.PrereleaseBump{Pre: "SNAPSHOT", PreWithoutVersion: true}, "1.2.4-SNAPSHOT"),
+	Entry("without an identifier from a final version", "1.2.3", version.PrereleaseBump{}, "1.2.4-1"),
+	Entry("without an identifier from a prerelease", "1.2.4-rc.1", version.PrereleaseBump{}, "1.2.4-rc.2"),
+	Entry("without an identifier from a numbered prerelease", "1.2.4-3", version.PrereleaseBump{}, "1.2.4-4"),
+	Entry("without an identifier bumping the last number", "1.2.4-rc.1.alpha.2", version.PrereleaseBump{}, "1.2.4-rc.1.alpha.3"),
+	Entry("without an identifier from a prerelease without a number", "1.2.4-rc", version.PrereleaseBump{}, "1.2.4-rc.1"),
+)
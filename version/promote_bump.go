@@ -0,0 +1,26 @@
+package version
+
+import "github.com/blang/semver"
+
+// PromoteBump moves a prerelease to the next of the channels, e.g.
+// 1.0.0-alpha.3 -> 1.0.0-beta.1 -> 1.0.0-rc.1 -> 1.0.0. It refuses versions
+// that cannot be promoted; see PreReleaseChannels.Promote.
+type PromoteBump struct {
+	Channels          PreReleaseChannels
+	PreWithoutVersion bool
+}
+
+// Apply leaves versions that cannot be promoted as they are, as it cannot
+// refuse them. Use CheckedApply, or the Apply function, instead.
+func (bump PromoteBump) Apply(v semver.Version) semver.Version {
+	promoted, err := bump.CheckedApply(v)
+	if err != nil {
+		return v
+	}
+
+	return promoted
+}
+
+func (bump PromoteBump) CheckedApply(v semver.Version) (semver.Version, error) {
+	return bump.Channels.Promote(v, bump.PreWithoutVersion)
+}
@@ -0,0 +1,35 @@
+package version_test
+
+import (
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PromoteBump", func() {
+	bump := version.PromoteBump{Channels: version.PreReleaseChannels{"alpha", "beta", "rc"}}
+
+	DescribeTable("CheckedApply",
+		func(input string, output string) {
+			promoted, err := bump.CheckedApply(semver.MustParse(input))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(promoted.String()).To(Equal(output))
+		},
+		Entry("to the next channel", "1.0.0-alpha.3", "1.0.0-beta.1"),
+		Entry("to the final version", "1.0.0-rc.1", "1.0.0"),
+	)
+
+	DescribeTable("refusing versions that cannot be promoted",
+		func(input string, message string) {
+			_, err := version.Apply(bump, semver.MustParse(input))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("a final version", "1.0.0", "already a final version"),
+		Entry("a prerelease outside of the channels", "1.0.0-dev.2", "not one of the channels"),
+	)
+
+	It("leaves versions that cannot be promoted alone when applied unchecked", func() {
+		Expect(bump.Apply(semver.MustParse("1.0.0-dev.2")).String()).To(Equal("1.0.0-dev.2"))
+	})
+})
@@ -0,0 +1,15 @@
+package version_test
+
+import (
+	"github.com/blang/semver"
+	"github.com/concourse/semver-resource/version"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SetBump", func() {
+	It("replaces the version", func() {
+		bump := version.SetBump{Version: semver.MustParse("5.0.0")}
+		Expect(bump.Apply(semver.MustParse("1.2.3-rc.1"))).To(Equal(semver.MustParse("5.0.0")))
+	})
+})